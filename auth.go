@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+// Credentials provide tokens for talking to a GitHub instance.
+type Credentials interface {
+	// TokenSource returns the token source for repositories owned by owner.
+	TokenSource(ctx context.Context, owner string) (oauth2.TokenSource, error)
+}
+
+// TokenCredentials use the same static token, such as a personal access token, for every owner.
+type TokenCredentials struct {
+	ts oauth2.TokenSource
+}
+
+func NewTokenCredentials(token string) *TokenCredentials {
+	return &TokenCredentials{ts: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})}
+}
+
+func (c *TokenCredentials) TokenSource(_ context.Context, _ string) (oauth2.TokenSource, error) {
+	return c.ts, nil
+}
+
+// AppCredentials authenticate as a GitHub App installation. Installation
+// tokens are minted on demand and refreshed before they expire.
+type AppCredentials struct {
+	api   string
+	appID int64
+	key   *rsa.PrivateKey
+	// installation is fixed if non-zero, otherwise it is discovered for each owner.
+	installation int64
+	hc           *http.Client
+
+	mu      sync.Mutex
+	sources map[int64]oauth2.TokenSource
+	// installations caches the installation discovered for each owner.
+	installations map[string]int64
+}
+
+// restAPIURL returns the REST API base URL for the GitHub instance at base.
+func restAPIURL(base string) string {
+	base = strings.TrimSuffix(base, "/")
+	if base == defaultGitHubURL {
+		return "https://api.github.com"
+	}
+	return base + "/api/v3"
+}
+
+// NewAppCredentials returns credentials for the GitHub App appID, using the
+// PEM-encoded private key at keyPath. If installation is zero, the installation
+// is looked up for each repository owner.
+func NewAppCredentials(base string, appID int64, keyPath string, installation int64) (*AppCredentials, error) {
+	bs, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(bs)
+	if err != nil {
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+
+	return &AppCredentials{
+		api:           restAPIURL(base),
+		appID:         appID,
+		key:           key,
+		installation:  installation,
+		hc:            &http.Client{Timeout: 30 * time.Second},
+		sources:       map[int64]oauth2.TokenSource{},
+		installations: map[string]int64{},
+	}, nil
+}
+
+func (c *AppCredentials) TokenSource(ctx context.Context, owner string) (oauth2.TokenSource, error) {
+	id := c.installation
+	if id == 0 {
+		c.mu.Lock()
+		id = c.installations[owner]
+		c.mu.Unlock()
+	}
+	if id == 0 {
+		var err error
+		id, err = c.installationID(ctx, owner)
+		if err != nil {
+			return nil, fmt.Errorf("installation for %s: %w", owner, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.installation == 0 {
+		c.installations[owner] = id
+	}
+
+	if ts, ok := c.sources[id]; ok {
+		return ts, nil
+	}
+	ts := oauth2.ReuseTokenSource(nil, &installationTokenSource{creds: c, id: id})
+	c.sources[id] = ts
+	return ts, nil
+}
+
+// appJWT returns a short-lived JWT that authenticates as the app itself.
+func (c *AppCredentials) appJWT() (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		// Allow for clock drift, as recommended by GitHub
+		IssuedAt:  jwt.NewNumericDate(now.Add(-60 * time.Second)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+		Issuer:    fmt.Sprintf("%d", c.appID),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(c.key)
+}
+
+// do sends an API request authenticated as the app and decodes the JSON response into v.
+func (c *AppCredentials) do(ctx context.Context, method string, path string, v interface{}) (int, error) {
+	token, err := c.appJWT()
+	if err != nil {
+		return 0, fmt.Errorf("jwt: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(bs)))
+	}
+	return resp.StatusCode, json.Unmarshal(bs, v)
+}
+
+// installationID finds the installation of the app for an organization or user.
+func (c *AppCredentials) installationID(ctx context.Context, owner string) (int64, error) {
+	inst := struct {
+		ID int64 `json:"id"`
+	}{}
+
+	code, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/orgs/%s/installation", owner), &inst)
+	if code == http.StatusNotFound {
+		_, err = c.do(ctx, http.MethodGet, fmt.Sprintf("/users/%s/installation", owner), &inst)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return inst.ID, nil
+}
+
+// installationTokenSource mints a new installation access token each time it is called.
+type installationTokenSource struct {
+	creds *AppCredentials
+	id    int64
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if _, err := s.creds.do(ctx, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", s.id), &t); err != nil {
+		return nil, fmt.Errorf("installation token: %w", err)
+	}
+
+	return &oauth2.Token{AccessToken: t.Token, TokenType: "token", Expiry: t.ExpiresAt}, nil
+}
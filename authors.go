@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxOffenders is the number of authors named when flagging unsigned or unreviewed commits.
+const maxOffenders = 3
+
+// botRE matches the logins of well-known bots, along with anything GitHub marks as an app.
+var botRE = regexp.MustCompile(`(?i)(\[bot\]$|-bot$|^(dependabot|renovate|github-actions|mergify|snyk-bot|greenkeeper|imgbot|allcontributors|pre-commit-ci|k8s-ci-robot|web-flow|github)$)`)
+
+// authorStats tallies the commit hygiene of a single author.
+type authorStats struct {
+	Login      string
+	Bot        bool
+	Commits    int
+	Signed     int
+	Reviewed   int
+	Approved   int
+	PR         int
+	Unsigned   int
+	Unreviewed int
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+func (a *authorStats) String() string {
+	return fmt.Sprintf("%d commits: %.1f%% signed, %.1f%% reviewed, %.1f%% approved, %.1f%% with a PR",
+		a.Commits, percent(a.Signed, a.Commits), percent(a.Reviewed, a.Commits), percent(a.Approved, a.Commits), percent(a.PR, a.Commits))
+}
+
+// commitAuthor returns the login a commit is attributed to.
+func commitAuthor(c Commit) string {
+	switch {
+	case c.Author.Login != "":
+		return c.Author.Login
+	case c.Committer.Login != "":
+		return c.Committer.Login
+	}
+	return "unknown"
+}
+
+// offenders returns up to maxOffenders humans with the highest count, as "login (n)".
+func offenders(humans []*authorStats, count func(*authorStats) int) []string {
+	sorted := append([]*authorStats{}, humans...)
+	sort.SliceStable(sorted, func(i, j int) bool { return count(sorted[i]) > count(sorted[j]) })
+
+	names := []string{}
+	for _, a := range sorted {
+		if len(names) == maxOffenders || count(a) == 0 {
+			break
+		}
+		names = append(names, fmt.Sprintf("%s (%d)", a.Login, count(a)))
+	}
+	return names
+}
+
+// CheckCommitAuthors breaks the statistics of CheckCommits down by author.
+// Its results are informational: they do not add to the YOLO score.
+func CheckCommitAuthors(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	cs, err := c.Forge.Commits(ctx, c.Owner, c.Name, c.Ref, c.History)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commits: %w", err)
+	}
+	if len(cs) == 0 {
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("No commits found for %s", c.Ref)}}}, nil
+	}
+
+	byLogin := map[string]*authorStats{}
+	for _, co := range cs {
+		login := commitAuthor(co)
+		a, ok := byLogin[login]
+		if !ok {
+			a = &authorStats{Login: login, Bot: botRE.MatchString(login)}
+			byLogin[login] = a
+		}
+
+		a.Commits++
+		if co.Signed {
+			a.Signed++
+		} else {
+			a.Unsigned++
+		}
+		if co.Reviewed {
+			a.Reviewed++
+		} else {
+			a.Unreviewed++
+		}
+		if co.Approved {
+			a.Approved++
+		}
+		if co.AssociatedMergeRequest.Number > 0 {
+			a.PR++
+		}
+	}
+
+	humans := []*authorStats{}
+	bots := []*authorStats{}
+	for _, a := range byLogin {
+		if a.Bot {
+			bots = append(bots, a)
+		} else {
+			humans = append(humans, a)
+		}
+	}
+	for _, as := range [][]*authorStats{humans, bots} {
+		sort.Slice(as, func(i, j int) bool {
+			if as[i].Commits != as[j].Commits {
+				return as[i].Commits > as[j].Commits
+			}
+			return as[i].Login < as[j].Login
+		})
+	}
+
+	res := []Result{}
+	botCommits := 0
+	for _, a := range bots {
+		botCommits += a.Commits
+	}
+	res = append(res, Result{Msg: fmt.Sprintf("%d of the last %d commits were made by %d humans, %d by %d bots", len(cs)-botCommits, len(cs), len(humans), botCommits, len(bots))})
+
+	if names := offenders(humans, func(a *authorStats) int { return a.Unsigned }); len(names) > 0 {
+		res = append(res, Result{Msg: fmt.Sprintf("Most unsigned commits: %s", strings.Join(names, ", "))})
+	}
+	if names := offenders(humans, func(a *authorStats) int { return a.Unreviewed }); len(names) > 0 {
+		res = append(res, Result{Msg: fmt.Sprintf("Most unreviewed commits: %s", strings.Join(names, ", "))})
+	}
+
+	for _, a := range humans {
+		res = append(res, Result{Msg: fmt.Sprintf("%s: %s", a.Login, a)})
+	}
+	for _, a := range bots {
+		res = append(res, Result{Msg: fmt.Sprintf("%s (bot): %s", a.Login, a)})
+	}
+
+	return &Outcome{Results: res}, nil
+}
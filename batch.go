@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/klog/v2"
+)
+
+// Target is a single repository (and optional image) to scan in batch mode.
+type Target struct {
+	Repo  string `json:"repo"`
+	Image string `json:"image,omitempty"`
+}
+
+// BatchResult is the outcome of scanning a single Target.
+type BatchResult struct {
+	Target
+	Report *Report `json:"report,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// readTargets parses targets, one per line. A line is either a JSON object
+// or a repository optionally followed by an image. Blank lines and lines
+// starting with # are ignored.
+func readTargets(r io.Reader) ([]Target, error) {
+	ts := []Target{}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		t := Target{}
+		if strings.HasPrefix(line, "{") {
+			if err := json.Unmarshal([]byte(line), &t); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) > 2 {
+				return nil, fmt.Errorf("line %d: expected a repo and an optional image, got %q", n, line)
+			}
+			t.Repo = fields[0]
+			if len(fields) == 2 {
+				t.Image = fields[1]
+			}
+		}
+
+		if t.Repo == "" {
+			return nil, fmt.Errorf("line %d: missing repo", n)
+		}
+		ts = append(ts, t)
+	}
+	return ts, s.Err()
+}
+
+// runBatch scans every target with at most concurrency scans in flight. Each
+// scan is configured like base, sharing its cache and persistence layer.
+func runBatch(ctx context.Context, base *Config, ts []Target, concurrency int) []*BatchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]*BatchResult, len(ts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, t := range ts {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cf := *base
+			cf.Github = t.Repo
+			cf.Image = t.Image
+
+			klog.Infof("scanning %s ...", t.Repo)
+			br := &BatchResult{Target: t}
+			rep, err := runChecks(ctx, &cf)
+			if err != nil {
+				klog.Errorf("%s: %v", t.Repo, err)
+				br.Error = err.Error()
+			}
+			br.Report = rep
+			results[i] = br
+		}(i, t)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// rankResults sorts results from most to least YOLO. Failed scans come last.
+func rankResults(results []*BatchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].Report, results[j].Report
+		switch {
+		case a == nil || b == nil:
+			return a != nil
+		case a.Level != b.Level:
+			return a.Level > b.Level
+		default:
+			return a.Percent > b.Percent
+		}
+	})
+}
+
+// printLeaderboard writes a ranked table of results with a per-check breakdown.
+func printLeaderboard(w io.Writer, results []*BatchResult) {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, br := range results {
+		if br.Report == nil {
+			continue
+		}
+		for _, c := range br.Report.Checks {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "RANK\tREPO\tLEVEL\tSCORE")
+	for _, id := range ids {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(id))
+	}
+	fmt.Fprintln(tw)
+
+	failed := []*BatchResult{}
+	rank := 0
+	for _, br := range results {
+		if br.Report == nil {
+			failed = append(failed, br)
+			continue
+		}
+
+		rank++
+		rep := br.Report
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d/%d (%d%%)", rank, rep.Repo, -rep.Level, rep.Score, rep.MaxScore, rep.Percent)
+
+		checks := map[string]CheckReport{}
+		for _, c := range rep.Checks {
+			checks[c.ID] = c
+		}
+		for _, id := range ids {
+			c, ok := checks[id]
+			switch {
+			case !ok:
+				fmt.Fprintf(tw, "\t-")
+			case c.Error != "":
+				fmt.Fprintf(tw, "\terror")
+			case c.Skipped != "":
+				fmt.Fprintf(tw, "\tskip")
+			default:
+				score, maxScore := 0, 0
+				for _, r := range c.Results {
+					score += r.Score * c.Weight
+					maxScore += r.Max * c.Weight
+				}
+				fmt.Fprintf(tw, "\t%d/%d", score, maxScore)
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "\nFailed to scan %d repositories:\n", len(failed))
+		for _, br := range failed {
+			fmt.Fprintf(w, "  %s: %s\n", br.Repo, br.Error)
+		}
+	}
+}
+
+// batchMain runs batch mode for the CLI and returns the exit code.
+func batchMain(ctx context.Context, base *Config, policy *Policy, path string, concurrency int) int {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			klog.Errorf("open: %v", err)
+			return exitFailed
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ts, err := readTargets(r)
+	if err != nil {
+		klog.Errorf("read targets: %v", err)
+		return exitFailed
+	}
+
+	results := runBatch(ctx, base, ts, concurrency)
+	rankResults(results)
+	return reportBatch(os.Stdout, results, policy)
+}
+
+// reportBatch writes the results in the selected format and returns the exit code.
+func reportBatch(w io.Writer, results []*BatchResult, policy *Policy) int {
+	var err error
+	switch *formatFlag {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			SchemaVersion int            `json:"schemaVersion"`
+			Results       []*BatchResult `json:"results"`
+		}{SchemaVersion: reportSchemaVersion, Results: results})
+	default:
+		printLeaderboard(w, results)
+	}
+	if err != nil {
+		klog.Errorf("write report: %v", err)
+		return exitFailed
+	}
+
+	return batchExitCode(results, policy)
+}
+
+// batchExitCode applies the policy to each report and returns the most severe exit code.
+func batchExitCode(results []*BatchResult, policy *Policy) int {
+	code := exitPass
+	for _, br := range results {
+		if br.Report == nil {
+			code = exitFailed
+			continue
+		}
+		violations := policy.Violations(br.Report)
+		for _, v := range violations {
+			fmt.Fprintf(os.Stderr, "%s: policy violation: %s\n", br.Repo, v)
+		}
+		if c := exitCode(br.Report, violations); c > code {
+			code = c
+		}
+	}
+	return code
+}
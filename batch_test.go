@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTargets(t *testing.T) {
+	in := `# repositories to scan
+chainguard-dev/yoloc
+
+sigstore/cosign gcr.io/projectsigstore/cosign
+{"repo": "https://gitlab.com/group/project", "image": "registry.gitlab.com/group/project"}
+`
+	want := []Target{
+		{Repo: "chainguard-dev/yoloc"},
+		{Repo: "sigstore/cosign", Image: "gcr.io/projectsigstore/cosign"},
+		{Repo: "https://gitlab.com/group/project", Image: "registry.gitlab.com/group/project"},
+	}
+
+	got, err := readTargets(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("readTargets: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readTargets() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadTargetsErrors(t *testing.T) {
+	tests := map[string]string{
+		"too many fields": "owner/name image extra",
+		"invalid JSON":    `{"repo": `,
+		"missing repo":    `{"image": "gcr.io/acme/app"}`,
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			if ts, err := readTargets(strings.NewReader("owner/ok\n" + in)); err == nil {
+				t.Errorf("readTargets(%q) = %+v, want error", in, ts)
+			} else if !strings.HasPrefix(err.Error(), "line 2: ") {
+				t.Errorf("readTargets(%q) error = %q, want it to name line 2", in, err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -15,7 +16,6 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/hashicorp/go-version"
 	lru "github.com/hnlq715/golang-lru"
-	"github.com/shurcooL/githubv4"
 	"k8s.io/klog/v2"
 
 	"github.com/sigstore/cosign/cmd/cosign/cli/fulcio"
@@ -25,63 +25,41 @@ import (
 )
 
 type Config struct {
-	Github      string
-	Image       string
-	V4Client    *githubv4.Client
-	Cache       *lru.ARCCache
-	Owner       string
-	Name        string
-	Branch      string
-	FoundImages []string
-	Persist     Persister
+	Github string
+	// Path is a local checkout to scan instead of cloning Github.
+	Path  string
+	Image string
+	// Ref is the branch, tag or commit to analyze. If empty, runChecks sets it to the default branch.
+	Ref string
+	// DefaultBranch is the repository's default branch, resolved by runChecks.
+	DefaultBranch string
+	// History controls how much commit history is analyzed.
+	History HistoryOptions
+	Forges  *Forges
+	// Forge hosts the repository; it is resolved from Github by runChecks.
+	Forge      Forge
+	Cache      *lru.ARCCache
+	Owner      string
+	Name       string
+	Persist    Persister
+	Checks     []string
+	SkipChecks []string
 }
 
 type Result struct {
-	Score int
-	Max   int
-	Msg   string
-	Level int
+	Score     int        `json:"score"`
+	Max       int        `json:"max"`
+	Msg       string     `json:"message"`
+	Level     int        `json:"level"`
+	Locations []Location `json:"locations,omitempty"`
 }
 
-func pageRE(ctx context.Context, url, regex string) (bool, error) {
-	bs, err := getCtx(ctx, url)
-	if err != nil {
-		return false, fmt.Errorf("get: %w", err)
-	}
-	return regexp.MustCompile(regex).MatchString(string(bs)), nil
-}
-
-func CheckSBOM(ctx context.Context, c *Config) ([]Result, error) {
-	res := []Result{}
-	level := 1
-
-	ok, err := pageRE(ctx, fmt.Sprintf("https://github.com/%s", c.Github), ("(?i)sbom|spdx"))
-	if err != nil {
-		return nil, fmt.Errorf("page: %w", err)
-	}
-
-	found := []string{}
-
-	if ok {
-		found = append(found, "main page")
-	}
-
-	ok, err = pageRE(ctx, fmt.Sprintf("https://github.com/%s/releases", c.Github), ("(?i)sbom|spdx"))
-	if err != nil {
-		return nil, fmt.Errorf("page: %w", err)
-	}
-	if ok {
-		found = append(found, "releases page")
-	}
-
-	if len(found) == 0 {
-		res = append(res, Result{Msg: "No evidence of SBOM usage on main or releases page", Score: 10, Max: 10, Level: level})
-	} else {
-		res = append(res, Result{Msg: fmt.Sprintf("Found evidence of SBOM usage on %s", strings.Join(found, ", ")), Score: 0, Max: 10, Level: level})
-
-	}
-
-	return res, nil
+// Location points at what a Result is about: a file within the repository
+// (Path, and optionally Line), or a web page (URL).
+type Location struct {
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+	URL  string `json:"url,omitempty"`
 }
 
 func pickTagToAnalyze(vs []string) string {
@@ -115,16 +93,16 @@ func pickTagToAnalyze(vs []string) string {
 	return vs[0]
 }
 
-func CheckSignedImage(ctx context.Context, c *Config) ([]Result, error) {
+func CheckSignedImage(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
 	images := []string{}
 	if c.Image != "" {
 		images = append(images, c.Image)
 	} else {
-		images = append(images, c.FoundImages...)
+		images = append(images, in.Images...)
 	}
 
 	if len(images) == 0 {
-		return []Result{{Msg: "no image"}}, nil
+		return &Outcome{Results: []Result{{Msg: "no image"}}}, nil
 	}
 
 	res := []Result{}
@@ -193,38 +171,27 @@ func CheckSignedImage(ctx context.Context, c *Config) ([]Result, error) {
 		}
 	}
 
-	return res, nil
+	return &Outcome{Results: res}, nil
 }
 
-func CheckCommits(_ context.Context, c *Config) ([]Result, error) {
+func CheckCommits(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
 	res := []Result{}
 
 	signed := 0
+	byGitHub := 0
+	types := map[string]int{}
 	approved := 0
 	commits := 0
 	pr := 0
 	reviewed := 0
 
-	if c.Branch == "" {
-		c.Branch = "main"
-	}
-
-	cs, err := Commits(c.V4Client, c.Owner, c.Name, c.Branch, c.Cache)
+	cs, err := c.Forge.Commits(ctx, c.Owner, c.Name, c.Ref, c.History)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get commits: %w", err)
 	}
 
 	if len(cs) == 0 {
-		c.Branch = "master"
-		cs, err = Commits(c.V4Client, c.Owner, c.Name, c.Branch, c.Cache)
-		if err != nil {
-			return nil, fmt.Errorf("unable to get commits: %w", err)
-		}
-	}
-
-	if len(cs) == 0 {
-		c.Branch = "unknown"
-		return []Result{{Msg: "No commits found"}}, nil
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("No commits found for %s", c.Ref)}}}, nil
 	}
 
 	newest := time.Time{}
@@ -233,8 +200,15 @@ func CheckCommits(_ context.Context, c *Config) ([]Result, error) {
 			newest = co.CommittedDate
 		}
 		commits++
-		if co.Signed {
+		switch {
+		case co.Signed:
 			signed++
+			types[co.SignatureType]++
+		case co.SignatureType == "gitsign":
+			types["gitsign (unverified)"]++
+		}
+		if co.SignedByGitHub {
+			byGitHub++
 		}
 		if co.Approved {
 			approved++
@@ -250,7 +224,19 @@ func CheckCommits(_ context.Context, c *Config) ([]Result, error) {
 	}
 
 	percSigned := (float64(signed) / float64(commits))
-	res = append(res, Result{Msg: fmt.Sprintf("%.1f%% of the last %d commits were signed. ", percSigned*100, len(cs)), Score: 5 - int(math.Ceil(5*percSigned)), Max: 5})
+	res = append(res, Result{Msg: fmt.Sprintf("%.1f%% of the last %d commits were signed by their developer. ", percSigned*100, len(cs)), Score: 5 - int(math.Ceil(5*percSigned)), Max: 5})
+
+	kinds := []string{}
+	for _, t := range []string{"gpg", "ssh", "smime", "gitsign", "gitsign (unverified)"} {
+		if types[t] > 0 {
+			kinds = append(kinds, fmt.Sprintf("%s: %d", t, types[t]))
+		}
+	}
+	msg := fmt.Sprintf("%.1f%% of the last %d commits were signed by GitHub on merge", float64(byGitHub)/float64(commits)*100, len(cs))
+	if len(kinds) > 0 {
+		msg = fmt.Sprintf("%s; developer signatures by type: %s", msg, strings.Join(kinds, ", "))
+	}
+	res = append(res, Result{Msg: msg})
 
 	percApproved := (float64(approved) / float64(commits))
 	res = append(res, Result{Msg: fmt.Sprintf("%.1f%% of the last %d commits were approved.", percApproved*100, len(cs)), Score: 10 - int(math.Ceil(10*percApproved)), Max: 10, Level: 1})
@@ -268,42 +254,78 @@ func CheckCommits(_ context.Context, c *Config) ([]Result, error) {
 		res = append(res, Result{Msg: fmt.Sprintf("Last commit was %d days ago (active)", staleDays), Score: 0, Max: 5})
 	}
 
-	return res, nil
+	return &Outcome{Results: res}, nil
 }
 
-func getCtx(ctx context.Context, url string) ([]byte, error) {
+// getLimitCtx fetches url, reading no more than the first n bytes of the body.
+func getLimitCtx(ctx context.Context, client *http.Client, url string, n int64) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, n))
 }
 
-func CheckReleaserV2(ctx context.Context, c *Config) ([]Result, error) {
+// assetClient downloads release assets. Asset URLs redirect to a storage
+// host, so the forge's client, which adds its token to every request, must
+// not be used for them.
+var assetClient = &http.Client{Timeout: 2 * time.Minute}
+
+// releasesToAnalyze is the number of recent releases examined by CheckReleaserV2.
+const releasesToAnalyze = 10
+
+func CheckReleaserV2(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
 	res := []Result{}
-	bs, err := getCtx(ctx, fmt.Sprintf("https://github.com/%s/releases", c.Github))
+	rs, err := c.Forge.Releases(ctx, c.Owner, c.Name, releasesToAnalyze)
 	if err != nil {
 		return nil, err
 	}
 
-	matches := regexp.MustCompile(`alt="@(.*?)".*avatar-small`).FindStringSubmatch(string(bs))
-	if len(matches) == 0 {
+	if len(rs) == 0 {
 		res = append(res, Result{Score: 10, Max: 10, Msg: "No releases found? Nice!"})
-		return res, nil
+		return &Outcome{Results: res}, nil
 	}
 
-	if user := matches[1]; regexp.MustCompile(fmt.Sprintf("bot|action|release|build|jenkins|machine|auto|%s", c.Name)).MatchString(user) {
-		res = append(res, Result{Score: 0, Max: 10, Msg: fmt.Sprintf("Previous release was likely automated (%q)", user)})
-	} else {
-		res = append(res, Result{Score: 4, Max: 10, Msg: fmt.Sprintf("Releases found, last by %s (probably not fully automated)", user)})
+	botRE := regexp.MustCompile(fmt.Sprintf("bot|action|release|build|jenkins|machine|auto|%s", regexp.QuoteMeta(c.Name)))
+	humans := []string{}
+	unknown := 0
+	locs := []Location{}
+	for _, r := range rs {
+		switch {
+		case r.Automated || (r.Author != "" && botRE.MatchString(r.Author)):
+		case r.Author == "":
+			unknown++
+		default:
+			humans = append(humans, fmt.Sprintf("%s by %s", r.Tag, r.Author))
+			locs = append(locs, Location{URL: r.URL})
+		}
+	}
+
+	if unknown == len(rs) {
+		res = append(res, Result{Msg: fmt.Sprintf("The authors of the last %d releases are unknown", len(rs))})
+		return &Outcome{Results: res}, nil
+	}
+
+	// Releases whose author is unknown are left out rather than guessed at.
+	known := len(rs) - unknown
+	percAutomated := float64(known-len(humans)) / float64(known)
+	msg := fmt.Sprintf("%.1f%% of the last %d releases were likely automated", percAutomated*100, known)
+	if unknown > 0 {
+		msg = fmt.Sprintf("%s, excluding %d with an unknown author", msg, unknown)
+	}
+	if len(humans) > 0 {
+		msg = fmt.Sprintf("%s; cut by humans: %s", msg, strings.Join(humans, ", "))
 	}
-	return res, nil
+	res = append(res, Result{Score: int(math.Ceil(4 * (1 - percAutomated))), Max: 10, Msg: msg, Locations: locs})
+	return &Outcome{Results: res}, nil
 }
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// remoteRE returns a regular expression that extracts owner/name from common
+// remote URL forms for the GitHub instance at host.
+func remoteRE(host string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(host) + `[:/]([^/]+)/([^/]+?)(\.git)?/?$`)
+}
+
+var (
+	cloneMu sync.Mutex
+	// cloneLocks serializes checkouts of the same destination, so that checks
+	// running concurrently share a single complete clone.
+	cloneLocks = map[string]*sync.Mutex{}
+)
+
+// lockDest locks the clone destination dest, returning the unlock function.
+func lockDest(dest string) func() {
+	cloneMu.Lock()
+	l, ok := cloneLocks[dest]
+	if !ok {
+		l = &sync.Mutex{}
+		cloneLocks[dest] = l
+	}
+	cloneMu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// checkout returns the path to a working tree for the repository: the local
+// checkout when scanning with --path, or a clone of c.Ref. An empty path is
+// returned if there is no ref to check out.
+func checkout(ctx context.Context, c *Config) (string, error) {
+	if c.Path != "" {
+		return c.Path, nil
+	}
+	if c.Ref == "" {
+		return "", nil
+	}
+
+	cd, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+
+	dest := filepath.Join(cd, "yoloc", c.Owner, fmt.Sprintf("%s@%s", c.Name, url.PathEscape(c.Ref)))
+	defer lockDest(dest)()
+
+	if err := os.MkdirAll(dest, 0o700); err != nil {
+		return "", fmt.Errorf("cache dir: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err == nil {
+		_, err := git.PlainOpen(dest)
+		if err != nil {
+			return "", fmt.Errorf("clone: %w", err)
+		}
+		return dest, nil
+	}
+
+	auth, err := c.Forge.CloneAuth(ctx)
+	if err != nil {
+		return "", fmt.Errorf("clone auth: %w", err)
+	}
+
+	opts := &git.CloneOptions{
+		URL:               c.Forge.CloneURL(c.Owner, c.Name),
+		Auth:              auth,
+		SingleBranch:      true,
+		Depth:             1,
+		RecurseSubmodules: git.NoRecurseSubmodules,
+	}
+
+	// Branches and tags can be cloned shallowly.
+	for _, rn := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(c.Ref), plumbing.NewTagReferenceName(c.Ref)} {
+		opts.ReferenceName = rn
+		if _, err := git.PlainCloneContext(ctx, dest, false, opts); err == nil {
+			return dest, nil
+		}
+	}
+
+	// Anything else is a commit, which can only be checked out from a full clone.
+	opts.ReferenceName = ""
+	opts.SingleBranch = false
+	opts.Depth = 0
+	r, err := git.PlainCloneContext(ctx, dest, false, opts)
+	if err != nil {
+		return "", fmt.Errorf("clone: %w", err)
+	}
+
+	h, err := r.ResolveRevision(plumbing.Revision(c.Ref))
+	if err != nil {
+		os.RemoveAll(dest)
+		return "", fmt.Errorf("resolve %s: %w", c.Ref, err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("worktree: %w", err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Hash: *h}); err != nil {
+		os.RemoveAll(dest)
+		return "", fmt.Errorf("checkout %s: %w", c.Ref, err)
+	}
+	return dest, nil
+}
+
+// openLocal fills in the repository name for a local checkout. If the checkout
+// has a remote named origin on the configured GitHub instance, its owner and name are used. Otherwise the
+// directory name is used and the owner is left empty.
+func openLocal(cf *Config) error {
+	abs, err := filepath.Abs(cf.Path)
+	if err != nil {
+		return fmt.Errorf("abs: %w", err)
+	}
+
+	r, err := git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("open %s: %w", abs, err)
+	}
+	cf.Path = abs
+	cf.Ref = ""
+	if head, err := r.Head(); err == nil {
+		cf.Ref = head.Name().Short()
+		if !head.Name().IsBranch() {
+			cf.Ref = head.Hash().String()
+		}
+	}
+	cf.Owner = ""
+	cf.Name = filepath.Base(abs)
+	cf.Github = cf.Name
+
+	remote, err := r.Remote("origin")
+	if err != nil {
+		return nil
+	}
+	host := "github.com"
+	if cf.Forges != nil {
+		host = cf.Forges.GitHubHost()
+	}
+	re := remoteRE(host)
+	for _, u := range remote.Config().URLs {
+		if m := re.FindStringSubmatch(strings.TrimSpace(u)); m != nil {
+			cf.Owner = m[1]
+			cf.Name = m[2]
+			cf.Github = fmt.Sprintf("%s/%s", m[1], m[2])
+			break
+		}
+	}
+	return nil
+}
@@ -1,23 +1,76 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/pem"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	lru "github.com/hnlq715/golang-lru"
 	"github.com/shurcooL/githubv4"
 )
 
-var (
-	maxCommits   = 9
-	maxCommitAge = 365 * 24 * time.Hour
+// Defaults for HistoryOptions.
+const (
+	defaultHistoryCommits = 100
+	defaultHistoryAge     = 365 * 24 * time.Hour
+	defaultPullRequests   = 10
+	defaultReviews        = 10
 )
 
+// maxPageSize is the largest page GitHub will return for a connection.
+const maxPageSize = 100
+
+// maxQueryNodes is the most nodes GitHub lets a single GraphQL query request.
+const maxQueryNodes = 500000
+
+// HistoryOptions control how much commit history is analyzed.
+type HistoryOptions struct {
+	// Commits is the maximum number of commits to analyze.
+	Commits int
+	// Since excludes older commits. If zero, commits from the last year are analyzed.
+	Since time.Time
+	// PullRequests is the number of pull requests fetched for each commit.
+	PullRequests int
+	// Reviews is the number of reviews fetched for each pull request.
+	Reviews int
+}
+
+// since returns the time before which commits are ignored.
+func (o HistoryOptions) since() time.Time {
+	if o.Since.IsZero() {
+		return time.Now().Add(-defaultHistoryAge)
+	}
+	return o.Since
+}
+
+// commitsPage returns how many of the remaining commits to request in one
+// page, so that commits times pull requests times reviews stays within the
+// node limit of a query.
+func (o HistoryOptions) commitsPage(remaining int) int {
+	perCommit := min(o.PullRequests, maxPageSize) * min(o.Reviews, maxPageSize)
+	n := min(remaining, maxPageSize)
+	if perCommit > 0 {
+		n = min(n, maxQueryNodes/perCommit)
+	}
+	return n
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // Based heavily on code from https://github.com/ossf/scorecard - thanks guys!
 
 type graphqlData struct {
+	RateLimit  graphqlRateLimit
 	Repository struct {
 		Object struct {
 			Commit struct {
@@ -33,7 +86,9 @@ type graphqlData struct {
 						CommittedDate       githubv4.DateTime
 						Oid                 githubv4.GitObjectID
 						Author              struct {
-							User struct {
+							Name  githubv4.String
+							Email githubv4.String
+							User  struct {
 								Login githubv4.String
 							}
 						}
@@ -44,8 +99,15 @@ type graphqlData struct {
 							}
 						}
 						Signature struct {
+							Typename          githubv4.String `graphql:"__typename"`
 							IsValid           bool
 							WasSignedByGitHub bool
+							Signer            struct {
+								Login githubv4.String
+							}
+							Smime struct {
+								Signature githubv4.String
+							} `graphql:"... on SmimeSignature"`
 						}
 						AssociatedPullRequests struct {
 							Nodes []struct {
@@ -91,11 +153,20 @@ type graphqlData struct {
 }
 
 type Commit struct {
-	CommittedDate          time.Time
-	Message                string
-	SHA                    string
-	Committer              User
-	Signed                 bool
+	CommittedDate time.Time
+	Message       string
+	SHA           string
+	Author        User
+	Committer     User
+	// Signed is set if the developer signed the commit, as opposed to GitHub.
+	Signed bool
+	// SignatureType is gpg, ssh, smime or gitsign, or empty for unsigned commits.
+	// It is set even when the signature could not be verified.
+	SignatureType string
+	// Signer is the login of the user whose key made the signature, if known.
+	Signer string
+	// SignedByGitHub is set if GitHub's web-flow key signed the commit, such as when merging in the web UI.
+	SignedByGitHub         bool
 	Approved               bool
 	Reviewed               bool
 	AssociatedMergeRequest PullRequest
@@ -106,6 +177,7 @@ type PullRequest struct {
 	MergedAt time.Time
 	HeadSHA  string
 	Author   User
+	MergedBy User
 	Labels   []Label
 	Reviews  []Review
 }
@@ -123,6 +195,50 @@ type User struct {
 	Login string
 }
 
+// botEmailRE matches the noreply address that GitHub Apps commit with, capturing the bot's login.
+var botEmailRE = regexp.MustCompile(`^(?:\d+\+)?([^@]+\[bot\])@users\.noreply\.github\.com$`)
+
+// actorLogin returns the login of a git actor. GitHub does not link commits
+// made by bots to a user, so their login is recovered from the name or email.
+func actorLogin(login, name, email string) string {
+	switch {
+	case login != "":
+		return login
+	case botEmailRE.MatchString(email):
+		return botEmailRE.FindStringSubmatch(email)[1]
+	case strings.HasSuffix(name, "[bot]"):
+		return name
+	}
+	return ""
+}
+
+// signatureType classifies a commit signature by its GraphQL type. Sigstore's
+// gitsign makes S/MIME signatures with short-lived certificates that GitHub
+// cannot verify, so they are recognized by the issuer embedded in the signature.
+func signatureType(typename string, smime string) string {
+	switch typename {
+	case "GpgSignature":
+		return "gpg"
+	case "SshSignature":
+		return "ssh"
+	case "SmimeSignature":
+		if isGitsign(smime) {
+			return "gitsign"
+		}
+		return "smime"
+	}
+	return ""
+}
+
+// isGitsign reports whether a PEM-encoded S/MIME signature was made with a Sigstore certificate.
+func isGitsign(sig string) bool {
+	b, _ := pem.Decode([]byte(sig))
+	if b == nil {
+		return false
+	}
+	return bytes.Contains(b.Bytes, []byte("sigstore"))
+}
+
 func asSha256(o interface{}) string {
 	h := sha256.New()
 	h.Write([]byte(fmt.Sprintf("%v", o)))
@@ -130,34 +246,44 @@ func asSha256(o interface{}) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-func Commits(client *githubv4.Client, repoOwner, repoName string, branch string, l *lru.ARCCache) ([]Commit, error) {
+// Commits returns up to opts.Commits commits reachable from ref, newest
+// first, stopping at the first commit older than opts.Since. The newest commit
+// is always returned, so that abandoned repositories can be recognized.
+func Commits(ctx context.Context, client *githubv4.Client, repoOwner, repoName string, ref string, opts HistoryOptions, l *lru.ARCCache, limits *RateLimits) ([]Commit, error) {
 	query := &graphqlData{}
 	vars := map[string]interface{}{
 		"owner":                 githubv4.String(repoOwner),
 		"name":                  githubv4.String(repoName),
-		"pullRequestsToAnalyze": githubv4.Int(10),
-		"commitsToAnalyze":      githubv4.Int(10),
-		"reviewsToAnalyze":      githubv4.Int(10),
-		"expression":            githubv4.String(branch),
+		"pullRequestsToAnalyze": githubv4.Int(min(opts.PullRequests, maxPageSize)),
+		"commitsToAnalyze":      githubv4.Int(opts.commitsPage(opts.Commits)),
+		"reviewsToAnalyze":      githubv4.Int(min(opts.Reviews, maxPageSize)),
+		"expression":            githubv4.String(ref),
 		"commitsCursor":         (*githubv4.String)(nil),
 	}
 
-	ageCutoff := time.Now().Add(maxCommitAge * -1)
+	ageCutoff := opts.since()
 	ret := []Commit{}
 
-	varHashed := asSha256(vars)
+	varHashed := asSha256([]interface{}{vars, opts})
 	cached, exist := l.Get(varHashed)
 	if exist {
 		return cached.([]Commit), nil
 	}
 
 	for {
-		err := client.Query(context.Background(), &query, vars)
+		err := client.Query(ctx, query, vars)
 		if err != nil {
 			return nil, fmt.Errorf("query: %w", err)
 		}
+		limits.observeGraphQL(query.RateLimit)
 
+		done := false
 		for _, commit := range query.Repository.Object.Commit.History.Nodes {
+			if len(ret) >= opts.Commits || (len(ret) > 0 && commit.CommittedDate.Before(ageCutoff)) {
+				done = true
+				break
+			}
+
 			var committer string
 			if commit.Committer.User.Login != "" {
 				committer = string(commit.Committer.User.Login)
@@ -168,6 +294,8 @@ func Commits(client *githubv4.Client, repoOwner, repoName string, branch string,
 				committer = "github"
 			}
 
+			author := actorLogin(string(commit.Author.User.Login), string(commit.Author.Name), string(commit.Author.Email))
+
 			var associatedPR PullRequest
 
 			approved := false
@@ -183,11 +311,11 @@ func Commits(client *githubv4.Client, repoOwner, repoName string, branch string,
 					HeadSHA:  string(pr.HeadRefOid),
 					MergedAt: pr.MergedAt.Time,
 					Author:   User{Login: string(pr.Author.Login)},
+					MergedBy: User{Login: string(pr.MergedBy.Login)},
 				}
 
 				// Merging someone elses PR is considered tacit approval
-				if string(pr.MergedBy.Login) != string(commit.Author.User.Login) {
-					// log.Printf("#%d: tacit approval: pr merged by %s, commit owned by %s\ncommit: %+v\npr: %+v\n", pr.Number, pr.MergedBy.Login, commit.Author.User.Login, commit, pr)
+				if string(pr.MergedBy.Login) != author {
 					approved = true
 					reviewed = true
 				}
@@ -203,42 +331,44 @@ func Commits(client *githubv4.Client, repoOwner, repoName string, branch string,
 					}
 
 					if review.State == "APPROVED" {
-						//	log.Printf("#%d: found approval: %v", pr.Number, review)
 						approved = true
 					}
 				}
 				break
 			}
 
-			//			if !reviewed {
-			//				log.Printf("\n--------\nunreviewed commit: %+v\n--------\n", commit)
-			//			}
+			// GitHub cannot verify gitsign signatures, and a certificate that merely mentions
+			// Sigstore proves nothing, so they are typed but not counted as signed.
+			sigType := signatureType(string(commit.Signature.Typename), string(commit.Signature.Smime.Signature))
+			signed := commit.Signature.IsValid && !commit.Signature.WasSignedByGitHub
 
 			ret = append(ret, Commit{
 				CommittedDate:          commit.CommittedDate.Time,
 				SHA:                    string(commit.Oid),
+				Author:                 User{Login: author},
 				Committer:              User{Login: committer},
 				AssociatedMergeRequest: associatedPR,
-				Signed:                 commit.Signature.IsValid,
+				Signed:                 signed,
+				SignatureType:          sigType,
+				Signer:                 string(commit.Signature.Signer.Login),
+				SignedByGitHub:         commit.Signature.WasSignedByGitHub,
 				Approved:               approved,
 				Reviewed:               reviewed,
 			})
 
 			if commit.CommittedDate.Before(ageCutoff) {
+				done = true
 				break
 			}
 		}
 
-		if len(ret) >= maxCommits {
-			break
-		}
-
-		if !query.Repository.Object.Commit.History.PageInfo.HasNextPage {
+		if done || len(ret) >= opts.Commits || !query.Repository.Object.Commit.History.PageInfo.HasNextPage {
 			break
 		}
 		vars["commitsCursor"] = githubv4.NewString(query.Repository.Object.Commit.History.PageInfo.EndCursor)
-		l.Add(varHashed, ret)
+		vars["commitsToAnalyze"] = githubv4.Int(opts.commitsPage(opts.Commits - len(ret)))
 	}
 
+	l.Add(varHashed, ret)
 	return ret, nil
 }
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActorLogin(t *testing.T) {
+	tests := []struct {
+		login, name, email string
+		want               string
+	}{
+		{"alice", "Alice", "alice@example.com", "alice"},
+		{"", "dependabot[bot]", "49699333+dependabot[bot]@users.noreply.github.com", "dependabot[bot]"},
+		{"", "github-actions", "41898282+github-actions[bot]@users.noreply.github.com", "github-actions[bot]"},
+		{"", "renovate[bot]", "bot@renovateapp.com", "renovate[bot]"},
+		{"", "Alice", "alice@example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := actorLogin(tt.login, tt.name, tt.email); got != tt.want {
+			t.Errorf("actorLogin(%q, %q, %q) = %q, want %q", tt.login, tt.name, tt.email, got, tt.want)
+		}
+	}
+}
+
+// fakeHistory answers commit history queries for a branch with n commits,
+// one a day, newest first. Each query's page size is recorded in pages.
+func fakeHistory(n int, pages *[]int) func(string, map[string]interface{}) string {
+	now := time.Now()
+	return func(_ string, vars map[string]interface{}) string {
+		first := int(vars["commitsToAnalyze"].(float64))
+		*pages = append(*pages, first)
+		start := 0
+		if c, ok := vars["commitsCursor"].(string); ok {
+			start, _ = strconv.Atoi(c)
+		}
+		end := start + first
+		if end > n {
+			end = n
+		}
+
+		nodes := []string{}
+		for i := start; i < end; i++ {
+			date := now.Add(-time.Duration(i)*24*time.Hour - time.Hour).UTC().Format(time.RFC3339)
+			nodes = append(nodes, fmt.Sprintf(`{"oid": "%040d", "committedDate": %q, "author": {"user": {"login": "alice"}}, "committer": {"user": {"login": "alice"}}, "signature": null, "associatedPullRequests": {"nodes": []}}`, i, date))
+		}
+		return fmt.Sprintf(`{"data": {"rateLimit": {"cost": 1, "limit": 5000, "remaining": 4000, "resetAt": %q}, "repository": {"object": {"history": {"pageInfo": {"endCursor": "%d", "hasNextPage": %t}, "nodes": [%s]}}}}}`,
+			now.Add(time.Hour).UTC().Format(time.RFC3339), end, end < n, strings.Join(nodes, ","))
+	}
+}
+
+func TestCommitsHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   int
+		opts      HistoryOptions
+		want      int
+		wantPages []int
+	}{
+		{name: "fewer commits than a page", history: 400, opts: HistoryOptions{Commits: 5}, want: 5, wantPages: []int{5}},
+		{name: "more commits than a page", history: 400, opts: HistoryOptions{Commits: 150}, want: 150, wantPages: []int{100, 50}},
+		{name: "short history", history: 30, opts: HistoryOptions{Commits: 150}, want: 30, wantPages: []int{100}},
+		{name: "a year by default", history: 400, opts: HistoryOptions{Commits: 1000}, want: 365, wantPages: []int{100, 100, 100, 100}},
+		{name: "since", history: 400, opts: HistoryOptions{Commits: 1000, Since: time.Now().Add(-150 * 24 * time.Hour)}, want: 150, wantPages: []int{100, 100}},
+		{name: "large pull request and review pages shrink the commit page", history: 400, opts: HistoryOptions{Commits: 120, PullRequests: 100, Reviews: 100}, want: 120, wantPages: []int{50, 50, 20}},
+		{name: "the newest commit is always returned", history: 400, opts: HistoryOptions{Commits: 100, Since: time.Now()}, want: 1, wantPages: []int{100}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pages := []int{}
+			g, done := fakeGitHub(t, fakeHistory(tc.history, &pages))
+			defer done()
+
+			cs, err := g.Commits(context.Background(), "owner", "repo", "main", tc.opts)
+			if err != nil {
+				t.Fatalf("Commits: %v", err)
+			}
+			if len(cs) != tc.want {
+				t.Errorf("Commits() returned %d commits, want %d", len(cs), tc.want)
+			}
+			if !reflect.DeepEqual(pages, tc.wantPages) {
+				t.Errorf("Commits() fetched pages of %v, want %v", pages, tc.wantPages)
+			}
+			if l := g.RateLimits(); len(l) != 1 || l[0].Remaining != 4000 || l[0].Cost != 1 {
+				t.Errorf("RateLimits() = %+v, want the graphql budget from the last page", l)
+			}
+		})
+	}
+}
+
+func TestSignatureType(t *testing.T) {
+	gitsign := string(pem.EncodeToMemory(&pem.Block{Type: "SIGNED MESSAGE", Bytes: []byte("issuer sigstore-intermediate")}))
+	smime := string(pem.EncodeToMemory(&pem.Block{Type: "SIGNED MESSAGE", Bytes: []byte("issuer Example Corp CA")}))
+
+	tests := []struct {
+		typename, smime string
+		want            string
+	}{
+		{"GpgSignature", "", "gpg"},
+		{"SshSignature", "", "ssh"},
+		{"SmimeSignature", smime, "smime"},
+		{"SmimeSignature", gitsign, "gitsign"},
+		{"SmimeSignature", "not PEM, but mentions sigstore", "smime"},
+		{"UnknownSignature", "", ""},
+		{"", "", ""},
+	}
+	for _, tc := range tests {
+		if got := signatureType(tc.typename, tc.smime); got != tc.want {
+			t.Errorf("signatureType(%q, %.20q) = %q, want %q", tc.typename, tc.smime, got, tc.want)
+		}
+	}
+}
+
+func TestCommitSignatures(t *testing.T) {
+	gitsign, _ := json.Marshal(string(pem.EncodeToMemory(&pem.Block{Type: "SIGNED MESSAGE", Bytes: []byte("issuer sigstore-intermediate")})))
+	date := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	node := func(oid, committer, signature string) string {
+		return fmt.Sprintf(`{"oid": %q, "committedDate": %q, "author": {"user": {"login": "alice"}}, "committer": {"name": %q, "user": null}, "signature": %s, "associatedPullRequests": {"nodes": []}}`, oid, date, committer, signature)
+	}
+	nodes := []string{
+		node("gpg", "alice", `{"__typename": "GpgSignature", "isValid": true, "wasSignedByGitHub": false, "signer": {"login": "alice"}}`),
+		node("merge", "GitHub", `{"__typename": "GpgSignature", "isValid": true, "wasSignedByGitHub": true, "signer": {"login": "web-flow"}}`),
+		node("gitsign", "alice", fmt.Sprintf(`{"__typename": "SmimeSignature", "isValid": false, "wasSignedByGitHub": false, "signature": %s}`, gitsign)),
+		node("ssh", "alice", `{"__typename": "SshSignature", "isValid": true, "wasSignedByGitHub": false, "signer": {"login": "alice"}}`),
+		node("unsigned", "alice", `null`),
+	}
+	g, done := fakeGitHub(t, func(string, map[string]interface{}) string {
+		return fmt.Sprintf(`{"data": {"repository": {"object": {"history": {"pageInfo": {"hasNextPage": false}, "nodes": [%s]}}}}}`, strings.Join(nodes, ","))
+	})
+	defer done()
+
+	cs, err := g.Commits(context.Background(), "owner", "repo", "main", HistoryOptions{Commits: 10})
+	if err != nil {
+		t.Fatalf("Commits: %v", err)
+	}
+
+	type sig struct {
+		Signed         bool
+		SignatureType  string
+		Signer         string
+		SignedByGitHub bool
+		Committer      string
+	}
+	want := map[string]sig{
+		"gpg":      {Signed: true, SignatureType: "gpg", Signer: "alice"},
+		"merge":    {SignatureType: "gpg", Signer: "web-flow", SignedByGitHub: true, Committer: "github"},
+		"gitsign":  {SignatureType: "gitsign"},
+		"ssh":      {Signed: true, SignatureType: "ssh", Signer: "alice"},
+		"unsigned": {},
+	}
+	if len(cs) != len(want) {
+		t.Fatalf("got %d commits, want %d", len(cs), len(want))
+	}
+	for _, c := range cs {
+		got := sig{Signed: c.Signed, SignatureType: c.SignatureType, Signer: c.Signer, SignedByGitHub: c.SignedByGitHub, Committer: c.Committer.Login}
+		if got != want[c.SHA] {
+			t.Errorf("commit %s = %+v, want %+v", c.SHA, got, want[c.SHA])
+		}
+	}
+
+	o, err := CheckCommits(context.Background(), &Config{Forge: g, Owner: "owner", Name: "repo", Ref: "main", History: HistoryOptions{Commits: 10}}, Facts{})
+	if err != nil {
+		t.Fatalf("CheckCommits: %v", err)
+	}
+	wantMsgs := []string{
+		"40.0% of the last 5 commits were signed by their developer. ",
+		"20.0% of the last 5 commits were signed by GitHub on merge; developer signatures by type: gpg: 1, ssh: 1, gitsign (unverified): 1",
+	}
+	for i, m := range wantMsgs {
+		if o.Results[i].Msg != m {
+			t.Errorf("result %d = %q, want %q", i, o.Results[i].Msg, m)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var secretRE = regexp.MustCompile(`secrets\.(\w+)`)
+
+// publishTarget describes one way a workflow can deploy or publish, how to
+// tell it is using a stored secret, and how to tell it is using OIDC.
+type publishTarget struct {
+	name string
+	// step matches the step that deploys or publishes.
+	step *regexp.Regexp
+	// static matches lines that pass a stored secret as a credential.
+	static *regexp.Regexp
+	// keyless matches the configuration of a short-lived credential.
+	keyless *regexp.Regexp
+	// idToken is set when keyless credentials need the id-token: write permission.
+	idToken bool
+}
+
+var publishTargets = []publishTarget{
+	{
+		name:    "AWS",
+		step:    regexp.MustCompile(`configure-aws-credentials|aws-access-key-id|AWS_ACCESS_KEY_ID`),
+		static:  regexp.MustCompile(`(?i)aws[-_](access[-_]key[-_]id|secret[-_]access[-_]key)`),
+		keyless: regexp.MustCompile(`role-to-assume`),
+		idToken: true,
+	},
+	{
+		name:    "Google Cloud",
+		step:    regexp.MustCompile(`google-github-actions/auth|credentials_json|gcloud auth`),
+		static:  regexp.MustCompile(`(?i)credentials_json|GOOGLE_APPLICATION_CREDENTIALS|(sa|service_account)_key`),
+		keyless: regexp.MustCompile(`workload_identity_provider`),
+		idToken: true,
+	},
+	{
+		name:    "Azure",
+		step:    regexp.MustCompile(`azure/login`),
+		static:  regexp.MustCompile(`(?i)creds|client[-_]secret|AZURE_CREDENTIALS`),
+		keyless: regexp.MustCompile(`client-id`),
+		idToken: true,
+	},
+	{
+		name:    "npm",
+		step:    regexp.MustCompile(`\b(npm|yarn|pnpm) publish|npm-publish`),
+		static:  regexp.MustCompile(`(?i)npm_token|NODE_AUTH_TOKEN|token:`),
+		keyless: regexp.MustCompile(`--provenance|NPM_CONFIG_PROVENANCE|provenance: true`),
+		idToken: true,
+	},
+	{
+		name:    "PyPI",
+		step:    regexp.MustCompile(`gh-action-pypi-publish|twine upload|poetry publish|uv publish`),
+		static:  regexp.MustCompile(`(?i)pypi|TWINE_PASSWORD|password`),
+		keyless: regexp.MustCompile(`gh-action-pypi-publish`),
+		idToken: true,
+	},
+	{
+		name:    "container registry",
+		step:    regexp.MustCompile(`docker/login-action|\b(docker|podman|buildah) login|helm registry login|crane auth login`),
+		static:  regexp.MustCompile(`(?i)password|\slogin\b.*\s-p\b`),
+		keyless: regexp.MustCompile(`secrets\.GITHUB_TOKEN|github\.token|amazon-ecr-login|configure-docker`),
+	},
+}
+
+// storedSecrets returns the secrets, other than the GITHUB_TOKEN that Actions
+// issues for every run, on the lines of text matching re.
+func storedSecrets(text string, re *regexp.Regexp) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		for _, m := range secretRE.FindAllStringSubmatch(line, -1) {
+			if m[1] != "GITHUB_TOKEN" && !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// CheckCICredentials finds the jobs that deploy or publish, and whether they
+// authenticate with a long-lived secret or a short-lived OIDC credential.
+func CheckCICredentials(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
+	ws, o, err := workflowsFrom(in)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	res := []Result{}
+	for _, w := range ws {
+		for _, j := range w.Jobs {
+			// Stored secrets are only attributed to a target when they are
+			// passed to its step or inherited from the job or workflow
+			// environment, as other steps may use unrelated secrets. Keyless
+			// credentials are often set up by an earlier step, so the whole
+			// job is considered for them.
+			env := strings.Join(append(pairs(w.Env), pairs(j.Env)...), "\n")
+			text := strings.Join([]string{env, jobText(j)}, "\n")
+			t := jobScopes(w, j)
+			idToken := t.WriteAll || t.Scopes["id-token"] == "write"
+
+			for _, pt := range publishTargets {
+				var step *Step
+				for _, s := range j.Steps {
+					if pt.step.MatchString(s.Text()) {
+						step = s
+						break
+					}
+				}
+				if step == nil {
+					continue
+				}
+
+				where := fmt.Sprintf("%s uses %s", w.where(j, step), pt.name)
+				loc := []Location{{Path: w.Path, Line: step.Line}}
+				secrets := storedSecrets(env+"\n"+step.Text(), pt.static)
+				switch {
+				case len(secrets) > 0:
+					noun := "secret"
+					if len(secrets) > 1 {
+						noun = "secrets"
+					}
+					res = append(res, Result{Msg: fmt.Sprintf("%s with the stored %s %s", where, noun, strings.Join(secrets, ", ")), Score: 10, Max: 10, Level: 1, Locations: loc})
+				case pt.keyless.MatchString(text) && (idToken || !pt.idToken):
+					res = append(res, Result{Msg: fmt.Sprintf("%s with a short-lived credential", where), Score: 0, Max: 10, Level: 1, Locations: loc})
+				case pt.keyless.MatchString(text):
+					res = append(res, Result{Msg: fmt.Sprintf("%s with OIDC, but without the id-token: write permission", where), Score: 5, Max: 10, Level: 1, Locations: loc})
+				default:
+					res = append(res, Result{Msg: fmt.Sprintf("%s without a recognizable credential", where), Locations: loc})
+				}
+			}
+		}
+	}
+
+	if len(res) == 0 {
+		return &Outcome{Results: []Result{{Msg: "No workflows deploy or publish with cloud or registry credentials"}}}, nil
+	}
+	return &Outcome{Results: res}, nil
+}
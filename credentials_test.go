@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStoredSecrets(t *testing.T) {
+	var target publishTarget
+	for _, pt := range publishTargets {
+		if pt.name == "container registry" {
+			target = pt
+		}
+	}
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "password: ${{ secrets.DOCKERHUB_TOKEN }}", want: []string{"DOCKERHUB_TOKEN"}},
+		{text: "password: ${{ secrets.GITHUB_TOKEN }}", want: []string{}},
+		{text: "docker login -u me -p ${{ secrets.HUB }} docker.io", want: []string{"HUB"}},
+		// Secrets on lines that are not credentials are not reported.
+		{text: "username: ${{ secrets.HUB_USER }}\nregistry: docker.io", want: []string{}},
+		{text: "password: ${{ secrets.A }}\nDOCKER_PASSWORD: ${{ secrets.A }}", want: []string{"A"}},
+	}
+
+	for _, tc := range tests {
+		if got := storedSecrets(tc.text, target.static); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("storedSecrets(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCheckCICredentials(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/release.yml", []byte(`on: push
+env:
+  NODE_AUTH_TOKEN: ${{ secrets.NPM_TOKEN }}
+jobs:
+  npm:
+    steps:
+      - run: npm publish
+  pypi:
+    permissions:
+      id-token: write
+    steps:
+      - uses: pypa/gh-action-pypi-publish@release/v1
+  aws:
+    steps:
+      - uses: aws-actions/configure-aws-credentials@v1
+        with:
+          aws-access-key-id: ${{ secrets.AWS_ACCESS_KEY_ID }}
+          aws-secret-access-key: ${{ secrets.AWS_SECRET_ACCESS_KEY }}
+  gcp:
+    steps:
+      - uses: google-github-actions/auth@v1
+        with:
+          workload_identity_provider: projects/1/locations/global/workloadIdentityPools/ci/providers/github
+  ghcr:
+    steps:
+      - uses: docker/login-action@v2
+        with:
+          registry: ghcr.io
+          password: ${{ secrets.GITHUB_TOKEN }}
+  azure:
+    steps:
+      - uses: azure/login@v1
+  test:
+    steps:
+      - run: make test
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := CheckCICredentials(context.Background(), &Config{}, Facts{Workflows: []*Workflow{w}, WorkflowsLoaded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		msg   string
+		score int
+		max   int
+	}{
+		{msg: "job npm step #1 uses npm with the stored secret NPM_TOKEN", score: 10, max: 10},
+		{msg: "job pypi step pypa/gh-action-pypi-publish@release/v1 uses PyPI with a short-lived credential", score: 0, max: 10},
+		{msg: "uses AWS with the stored secrets AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY", score: 10, max: 10},
+		{msg: "uses Google Cloud with OIDC, but without the id-token: write permission", score: 5, max: 10},
+		{msg: "uses container registry with a short-lived credential", score: 0, max: 10},
+		{msg: "uses Azure without a recognizable credential", score: 0, max: 0},
+	}
+	if len(o.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(o.Results), len(want), o.Results)
+	}
+	for i, r := range o.Results {
+		if !strings.Contains(r.Msg, want[i].msg) || r.Score != want[i].score || r.Max != want[i].max {
+			t.Errorf("result %d = %q %d/%d, want %q %d/%d", i, r.Msg, r.Score, r.Max, want[i].msg, want[i].score, want[i].max)
+		}
+	}
+}
+
+// Secrets used by other steps of a publishing job are not attributed to it.
+func TestCheckCICredentialsMixedJob(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/release.yml", []byte(`on: push
+jobs:
+  release:
+    permissions:
+      id-token: write
+    steps:
+      - uses: codecov/codecov-action@v3
+        with:
+          token: ${{ secrets.CODECOV_TOKEN }}
+      - uses: some/uploader@v1
+        with:
+          password: ${{ secrets.UPLOADER_PASSWORD }}
+      - run: npm publish --provenance
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := CheckCICredentials(context.Background(), &Config{}, Facts{Workflows: []*Workflow{w}, WorkflowsLoaded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.Results) != 1 {
+		t.Fatalf("got %d results, want 1: %+v", len(o.Results), o.Results)
+	}
+	if r := o.Results[0]; !strings.Contains(r.Msg, "uses npm with a short-lived credential") || r.Score != 0 {
+		t.Errorf("result = %q %d/%d, want npm with a short-lived credential", r.Msg, r.Score, r.Max)
+	}
+}
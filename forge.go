@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	lru "github.com/hnlq715/golang-lru"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// Forge is a service that hosts git repositories, such as GitHub or GitLab.
+type Forge interface {
+	// Host is the hostname of the forge, for example github.com.
+	Host() string
+	// Commits returns recent commits reachable from ref, along with their review status.
+	Commits(ctx context.Context, owner, name, ref string, opts HistoryOptions) ([]Commit, error)
+	// Releases returns up to n releases, newest first.
+	Releases(ctx context.Context, owner, name string, n int) ([]Release, error)
+	// DefaultBranch returns the name of the default branch.
+	DefaultBranch(ctx context.Context, owner, name string) (string, error)
+	// RepoURL returns the web URL of the repository.
+	RepoURL(owner, name string) string
+	// CommitURL returns the web URL of a commit.
+	CommitURL(owner, name, sha string) string
+	// PullRequestURL returns the web URL of a pull request, or merge request on GitLab.
+	PullRequestURL(owner, name string, number int) string
+	// ReleasesURL returns the web URL of the repository's releases page.
+	ReleasesURL(owner, name string) string
+	// CloneURL returns the URL used to clone the repository.
+	CloneURL(owner, name string) string
+	// CloneAuth returns the credentials used to clone, or nil to clone anonymously.
+	CloneAuth(ctx context.Context) (transport.AuthMethod, error)
+}
+
+// historyTimeout bounds a commit history fetch shared between checks.
+const historyTimeout = 5 * time.Minute
+
+// detached carries the values of its parent context, but not its deadline or
+// cancellation, so that work shared between callers outlives any one of them.
+type detached struct{ context.Context }
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+// shared runs fn once for concurrent callers with the same key. fn is given a
+// context that is not cancelled when ctx is, so that the other callers still
+// get its result, and each caller stops waiting when its own ctx is done.
+func shared(ctx context.Context, g *singleflight.Group, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	ch := g.DoChan(key, func() (interface{}, error) {
+		fctx, cancel := context.WithTimeout(detached{ctx}, historyTimeout)
+		defer cancel()
+		return fn(fctx)
+	})
+	select {
+	case r := <-ch:
+		return r.Val, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type Release struct {
+	Tag    string
+	Author string
+	// Automated is set if the forge knows the release was published by a bot or app.
+	Automated bool
+	CreatedAt time.Time
+	URL       string
+	Assets    []Asset
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string
+	URL  string
+}
+
+// Forges resolves a repository reference to the forge that hosts it.
+type Forges struct {
+	// Default is the forge ("github" or "gitlab") used for references without a host.
+	Default string
+	// GitHubURL is the base URL of either github.com or a GitHub Enterprise Server instance.
+	GitHubURL   string
+	Credentials Credentials
+	// GitLabURL is the base URL of the GitLab instance used by default.
+	GitLabURL string
+	// GitLabToken is only ever sent to GitLabURL.
+	GitLabToken string
+	Cache       *lru.ARCCache
+
+	mu     sync.Mutex
+	github map[oauth2.TokenSource]Forge
+	gitlab map[string]Forge
+}
+
+// Resolve returns the forge hosting repo, along with the repository owner and
+// name. repo is either owner/name or the web URL of the repository. On GitLab
+// the owner may contain slashes, for repositories in subgroups.
+func (fs *Forges) Resolve(ctx context.Context, repo string) (Forge, string, string, error) {
+	host := ""
+	path := repo
+	if strings.Contains(repo, "://") {
+		u, err := url.Parse(repo)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("parse: %w", err)
+		}
+		host = u.Host
+		path = u.Path
+	}
+	path = strings.Trim(path, "/")
+
+	glURL, err := url.Parse(fs.GitLabURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("parse gitlab url: %w", err)
+	}
+
+	switch {
+	case host == "" && fs.Default == "gitlab", host == glURL.Host, host == "gitlab.com":
+		// Anything after /-/ is a page within the project, such as /-/tree/main
+		path, _, _ = strings.Cut(path, "/-/")
+		path = strings.TrimSuffix(path, ".git")
+		i := strings.LastIndex(path, "/")
+		if i <= 0 {
+			return nil, "", "", fmt.Errorf("%q is not of the form group/name", repo)
+		}
+
+		base := strings.TrimSuffix(fs.GitLabURL, "/")
+		if host != "" && host != glURL.Host {
+			base = fmt.Sprintf("https://%s", host)
+		}
+		f, err := fs.gitLab(base)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return f, path[:i], path[i+1:], nil
+
+	case host == "" || host == fs.GitHubHost():
+		parts := strings.Split(path, "/")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, "", "", fmt.Errorf("%q is not of the form owner/name", repo)
+		}
+		f, err := fs.gitHub(ctx, parts[0])
+		if err != nil {
+			return nil, "", "", err
+		}
+		return f, parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+
+	default:
+		return nil, "", "", fmt.Errorf("unknown forge host: %q", host)
+	}
+}
+
+// GitHubHost returns the hostname of the configured GitHub instance.
+func (fs *Forges) GitHubHost() string {
+	u, err := url.Parse(fs.GitHubURL)
+	if err != nil {
+		return fs.GitHubURL
+	}
+	return u.Host
+}
+
+// gitHub returns the GitHub forge, authenticated for repositories owned by owner.
+func (fs *Forges) gitHub(ctx context.Context, owner string) (Forge, error) {
+	ts, err := fs.Credentials.TokenSource(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("credentials: %w", err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f, ok := fs.github[ts]; ok {
+		return f, nil
+	}
+	f := NewGitHub(fs.GitHubURL, ts, fs.Cache)
+	if fs.github == nil {
+		fs.github = map[oauth2.TokenSource]Forge{}
+	}
+	fs.github[ts] = f
+	return f, nil
+}
+
+// V4Client returns a GitHub GraphQL client, authenticated for repositories owned by owner.
+func (fs *Forges) V4Client(ctx context.Context, owner string) (*githubv4.Client, error) {
+	f, err := fs.gitHub(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return f.(*GitHub).client, nil
+}
+
+// Budget is the rate limit budget of one set of GitHub credentials.
+type Budget struct {
+	Host   string      `json:"host"`
+	Limits []RateLimit `json:"limits"`
+}
+
+// Budgets returns the rate limit budget of each set of GitHub credentials in use.
+func (fs *Forges) Budgets() []Budget {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	bs := []Budget{}
+	for _, f := range fs.github {
+		g := f.(*GitHub)
+		bs = append(bs, Budget{Host: g.Host(), Limits: g.RateLimits()})
+	}
+	return bs
+}
+
+// gitLab returns the forge for the GitLab instance at base.
+func (fs *Forges) gitLab(base string) (Forge, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f, ok := fs.gitlab[base]; ok {
+		return f, nil
+	}
+
+	token := ""
+	if strings.TrimSuffix(fs.GitLabURL, "/") == base {
+		token = fs.GitLabToken
+	}
+
+	f, err := NewGitLab(base, token, fs.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: %w", err)
+	}
+	if fs.gitlab == nil {
+		fs.gitlab = map[string]Forge{}
+	}
+	fs.gitlab[base] = f
+	return f, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sync/singleflight"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		forges    *Forges
+		repo      string
+		wantHost  string
+		wantOwner string
+		wantName  string
+		wantErr   bool
+	}{
+		{
+			name:      "owner/name on github.com",
+			repo:      "chainguard-dev/yoloc",
+			wantHost:  "github.com",
+			wantOwner: "chainguard-dev",
+			wantName:  "yoloc",
+		},
+		{
+			name:      "github.com clone URL",
+			repo:      "https://github.com/chainguard-dev/yoloc.git",
+			wantHost:  "github.com",
+			wantOwner: "chainguard-dev",
+			wantName:  "yoloc",
+		},
+		{
+			name:      "GitHub Enterprise Server URL",
+			forges:    &Forges{GitHubURL: "https://github.example.com", GitLabURL: "https://gitlab.com"},
+			repo:      "https://github.example.com/acme/widget/",
+			wantHost:  "github.example.com",
+			wantOwner: "acme",
+			wantName:  "widget",
+		},
+		{
+			name:      "gitlab.com subgroup page",
+			repo:      "https://gitlab.com/group/sub/project/-/tree/main",
+			wantHost:  "gitlab.com",
+			wantOwner: "group/sub",
+			wantName:  "project",
+		},
+		{
+			name:      "self-hosted GitLab",
+			forges:    &Forges{GitHubURL: defaultGitHubURL, GitLabURL: "https://gitlab.example.com"},
+			repo:      "https://gitlab.example.com/group/project.git",
+			wantHost:  "gitlab.example.com",
+			wantOwner: "group",
+			wantName:  "project",
+		},
+		{
+			name:      "GitLab by default",
+			forges:    &Forges{Default: "gitlab", GitHubURL: defaultGitHubURL, GitLabURL: "https://gitlab.com"},
+			repo:      "group/project",
+			wantHost:  "gitlab.com",
+			wantOwner: "group",
+			wantName:  "project",
+		},
+		{
+			name:    "missing name",
+			repo:    "chainguard-dev",
+			wantErr: true,
+		},
+		{
+			name:    "GitLab project without a group",
+			repo:    "https://gitlab.com/project",
+			wantErr: true,
+		},
+		{
+			name:    "unknown host",
+			repo:    "https://example.com/owner/name",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := tc.forges
+			if fs == nil {
+				fs = &Forges{GitHubURL: defaultGitHubURL, GitLabURL: "https://gitlab.com"}
+			}
+			fs.Credentials = NewTokenCredentials("")
+
+			f, owner, name, err := fs.Resolve(context.Background(), tc.repo)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve(%q) = %s, %s/%s, want error", tc.repo, f.Host(), owner, name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", tc.repo, err)
+			}
+			if f.Host() != tc.wantHost || owner != tc.wantOwner || name != tc.wantName {
+				t.Errorf("Resolve(%q) = %s, %s/%s, want %s, %s/%s", tc.repo, f.Host(), owner, name, tc.wantHost, tc.wantOwner, tc.wantName)
+			}
+		})
+	}
+}
+
+func TestSharedOutlivesCaller(t *testing.T) {
+	var g singleflight.Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetched := make(chan error)
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		fetched <- ctx.Err()
+		return "history", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := shared(ctx, &g, "key", fn)
+		first <- err
+	}()
+	<-started
+
+	// The caller gives up, but the fetch carries on for any others sharing it.
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("caller error = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if err := <-fetched; err != nil {
+		t.Errorf("fetch context error = %v, want none", err)
+	}
+}
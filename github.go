@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	lru "github.com/hnlq715/golang-lru"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultGitHubURL is the base URL of github.com, as opposed to a GitHub Enterprise Server.
+const defaultGitHubURL = "https://github.com"
+
+// GitHub is the Forge for github.com or a GitHub Enterprise Server instance.
+type GitHub struct {
+	base   string
+	ts     oauth2.TokenSource
+	hc     *http.Client
+	client *githubv4.Client
+	cache  *lru.ARCCache
+	limits *RateLimits
+	// commits shares a history fetch between checks that need it at the same time.
+	commits singleflight.Group
+}
+
+// NewGitHub returns the forge for the GitHub instance at base, such as
+// https://github.com or https://github.example.com. Every request to it,
+// including clones, is authenticated with tokens from ts.
+func NewGitHub(base string, ts oauth2.TokenSource, cache *lru.ARCCache) *GitHub {
+	limits := &RateLimits{}
+	hc := &http.Client{Transport: &rateLimitTransport{base: &oauth2.Transport{Source: ts}, limits: limits}}
+	return &GitHub{
+		base:   strings.TrimSuffix(base, "/"),
+		ts:     ts,
+		hc:     hc,
+		client: newV4Client(base, hc),
+		cache:  cache,
+		limits: limits,
+	}
+}
+
+// newV4Client returns a GraphQL client for the GitHub instance at base.
+func newV4Client(base string, hc *http.Client) *githubv4.Client {
+	base = strings.TrimSuffix(base, "/")
+	if base == defaultGitHubURL {
+		return githubv4.NewClient(hc)
+	}
+	return githubv4.NewEnterpriseClient(base+"/api/graphql", hc)
+}
+
+func (g *GitHub) Host() string {
+	u, err := url.Parse(g.base)
+	if err != nil {
+		return g.base
+	}
+	return u.Host
+}
+
+func (g *GitHub) Commits(ctx context.Context, owner, name, ref string, opts HistoryOptions) ([]Commit, error) {
+	key := asSha256([]interface{}{owner, name, ref, opts})
+	v, err := shared(ctx, &g.commits, key, func(ctx context.Context) (interface{}, error) {
+		return Commits(ctx, g.client, owner, name, ref, opts, g.cache, g.limits)
+	})
+	if err != nil {
+		return nil, g.rateLimited(err)
+	}
+	return v.([]Commit), nil
+}
+
+// restRelease is a release as returned by the REST API. Unlike GraphQL,
+// which only has a User as the author, it says when a bot or app published it.
+type restRelease struct {
+	TagName   string    `json:"tag_name"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	// Author is null for releases whose author was deleted.
+	Author *struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"author"`
+	Assets []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (g *GitHub) Releases(ctx context.Context, owner, name string, n int) ([]Release, error) {
+	rs := []restRelease{}
+	p := fmt.Sprintf("/repos/%s/%s/releases?per_page=%d", url.PathEscape(owner), url.PathEscape(name), min(n, maxPageSize))
+	if err := g.get(ctx, p, &rs); err != nil {
+		return nil, fmt.Errorf("list releases: %w", err)
+	}
+
+	ret := []Release{}
+	for _, r := range rs {
+		rel := Release{
+			Tag:       r.TagName,
+			CreatedAt: r.CreatedAt,
+			URL:       r.HTMLURL,
+		}
+		if r.Author != nil {
+			rel.Author = r.Author.Login
+			// GitHub Apps, including GitHub Actions, publish as bot users.
+			rel.Automated = r.Author.Type == "Bot"
+		}
+		for _, a := range r.Assets {
+			rel.Assets = append(rel.Assets, Asset{Name: a.Name, URL: a.BrowserDownloadURL})
+		}
+		ret = append(ret, rel)
+	}
+	return ret, nil
+}
+
+// get sends a REST API request and decodes the JSON response into v.
+func (g *GitHub) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, restAPIURL(g.base)+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := g.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(bs)))
+	}
+	return json.Unmarshal(bs, v)
+}
+
+type defaultBranchQuery struct {
+	RateLimit  graphqlRateLimit
+	Repository struct {
+		DefaultBranchRef struct {
+			Name githubv4.String
+		}
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (g *GitHub) DefaultBranch(ctx context.Context, owner, name string) (string, error) {
+	query := &defaultBranchQuery{}
+	vars := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(name),
+	}
+	if err := g.client.Query(ctx, query, vars); err != nil {
+		return "", g.rateLimited(fmt.Errorf("query: %w", err))
+	}
+	g.limits.observeGraphQL(query.RateLimit)
+	return string(query.Repository.DefaultBranchRef.Name), nil
+}
+
+type branchProtectionQuery struct {
+	RateLimit  graphqlRateLimit
+	Repository struct {
+		ViewerCanAdminister bool
+		DefaultBranchRef    struct {
+			Name githubv4.String
+		}
+		Ref struct {
+			BranchProtectionRule struct {
+				Pattern                      githubv4.String
+				RequiresApprovingReviews     bool
+				RequiredApprovingReviewCount githubv4.Int
+				RequiresCommitSignatures     bool
+				RequiresStatusChecks         bool
+				AllowsForcePushes            bool
+				AllowsDeletions              bool
+				IsAdminEnforced              bool
+			}
+		} `graphql:"ref(qualifiedName: $ref)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type rulesetsQuery struct {
+	RateLimit  graphqlRateLimit
+	Repository struct {
+		Rulesets struct {
+			Nodes []struct {
+				Name        githubv4.String
+				Enforcement githubv4.String
+				Target      githubv4.String
+				Conditions  struct {
+					RefName struct {
+						Include []githubv4.String
+						Exclude []githubv4.String
+					}
+				}
+				Rules struct {
+					Nodes []struct {
+						Type       githubv4.String
+						Parameters struct {
+							PullRequest struct {
+								RequiredApprovingReviewCount githubv4.Int
+							} `graphql:"... on PullRequestParameters"`
+						}
+					}
+				} `graphql:"rules(first: 100)"`
+				BypassActors struct {
+					TotalCount githubv4.Int
+				} `graphql:"bypassActors(first: 1)"`
+			}
+		} `graphql:"rulesets(first: 100, includeParents: true)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// rulesetApplies reports whether a ruleset's ref name conditions include branch.
+func rulesetApplies(include, exclude []githubv4.String, branch string, defaultBranch bool) bool {
+	matches := func(patterns []githubv4.String) bool {
+		for _, p := range patterns {
+			switch p := string(p); {
+			case p == "~ALL", p == "~DEFAULT_BRANCH" && defaultBranch:
+				return true
+			default:
+				if ok, _ := path.Match(strings.TrimPrefix(p, "refs/heads/"), branch); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return matches(include) && !matches(exclude)
+}
+
+// Protection returns the protections enforced on branch by its branch
+// protection rule and any active rulesets.
+func (g *GitHub) Protection(ctx context.Context, owner, name, branch string) (*Protection, error) {
+	vars := map[string]interface{}{
+		"owner": githubv4.String(owner),
+		"name":  githubv4.String(name),
+		"ref":   githubv4.String("refs/heads/" + branch),
+	}
+	bp := &branchProtectionQuery{}
+	if err := g.client.Query(ctx, bp, vars); err != nil {
+		return nil, g.rateLimited(fmt.Errorf("query: %w", err))
+	}
+	g.limits.observeGraphQL(bp.RateLimit)
+
+	// Admins can bypass the protections if any rule that applies lets them.
+	p := &Protection{AllowsForcePushes: true, AllowsDeletions: true, Readable: bp.Repository.ViewerCanAdminister}
+	if r := bp.Repository.Ref.BranchProtectionRule; r.Pattern != "" {
+		p.Sources = append(p.Sources, fmt.Sprintf("branch protection rule %q", r.Pattern))
+		if r.RequiresApprovingReviews {
+			p.RequiredReviews = int(r.RequiredApprovingReviewCount)
+		}
+		p.RequiresSignatures = r.RequiresCommitSignatures
+		p.RequiresStatusChecks = r.RequiresStatusChecks
+		p.AllowsForcePushes = r.AllowsForcePushes
+		p.AllowsDeletions = r.AllowsDeletions
+		p.AdminsCanBypass = !r.IsAdminEnforced
+	}
+
+	// Rulesets are missing from older GitHub Enterprise Server releases.
+	rs := &rulesetsQuery{}
+	delete(vars, "ref")
+	if err := g.client.Query(ctx, rs, vars); err != nil {
+		if !strings.Contains(err.Error(), "doesn't exist on type") {
+			return nil, g.rateLimited(fmt.Errorf("query rulesets: %w", err))
+		}
+	}
+	g.limits.observeGraphQL(rs.RateLimit)
+
+	defaultBranch := string(bp.Repository.DefaultBranchRef.Name) == branch
+	for _, r := range rs.Repository.Rulesets.Nodes {
+		if r.Enforcement != "ACTIVE" || (r.Target != "" && r.Target != "BRANCH") {
+			continue
+		}
+		if !rulesetApplies(r.Conditions.RefName.Include, r.Conditions.RefName.Exclude, branch, defaultBranch) {
+			continue
+		}
+
+		p.Sources = append(p.Sources, fmt.Sprintf("ruleset %q", r.Name))
+		p.AdminsCanBypass = p.AdminsCanBypass || r.BypassActors.TotalCount > 0
+		for _, rule := range r.Rules.Nodes {
+			switch rule.Type {
+			case "PULL_REQUEST":
+				if n := int(rule.Parameters.PullRequest.RequiredApprovingReviewCount); n > p.RequiredReviews {
+					p.RequiredReviews = n
+				}
+			case "REQUIRED_SIGNATURES":
+				p.RequiresSignatures = true
+			case "REQUIRED_STATUS_CHECKS":
+				p.RequiresStatusChecks = true
+			case "NON_FAST_FORWARD":
+				p.AllowsForcePushes = false
+			case "DELETION":
+				p.AllowsDeletions = false
+			}
+		}
+	}
+
+	if len(p.Sources) == 0 {
+		p.AdminsCanBypass = true
+	}
+	return p, nil
+}
+
+// rateLimited returns a RateLimitError in place of err if err reports that the
+// GraphQL budget is exhausted. GraphQL reports this as a RATE_LIMITED query
+// error rather than an HTTP status, and the error type is not exposed.
+func (g *GitHub) rateLimited(err error) error {
+	if !strings.Contains(strings.ToLower(err.Error()), "rate limit") {
+		return err
+	}
+	if rle := g.limits.exhausted("graphql"); rle != nil {
+		return rle
+	}
+	return err
+}
+
+// RateLimits returns the rate limit budget of the credentials used for this forge.
+func (g *GitHub) RateLimits() []RateLimit {
+	return g.limits.Snapshot()
+}
+
+func (g *GitHub) RepoURL(owner, name string) string {
+	return fmt.Sprintf("%s/%s/%s", g.base, owner, name)
+}
+
+func (g *GitHub) CommitURL(owner, name, sha string) string {
+	return fmt.Sprintf("%s/commit/%s", g.RepoURL(owner, name), sha)
+}
+
+func (g *GitHub) PullRequestURL(owner, name string, number int) string {
+	return fmt.Sprintf("%s/pull/%d", g.RepoURL(owner, name), number)
+}
+
+func (g *GitHub) ReleasesURL(owner, name string) string {
+	return g.RepoURL(owner, name) + "/releases"
+}
+
+func (g *GitHub) CloneURL(owner, name string) string {
+	return g.RepoURL(owner, name) + ".git"
+}
+
+func (g *GitHub) CloneAuth(_ context.Context) (transport.AuthMethod, error) {
+	t, err := g.ts.Token()
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	if t.AccessToken == "" {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: t.AccessToken}, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	lru "github.com/hnlq715/golang-lru"
+	"golang.org/x/oauth2"
+)
+
+// fakeGitHub serves the GraphQL and REST APIs of a GitHub Enterprise Server,
+// answering each GraphQL request with the response returned by respond for
+// its query and variables, and each REST request with the response for its
+// path and query string, without variables.
+func fakeGitHub(t *testing.T, respond func(query string, vars map[string]interface{}) string) (*GitHub, func()) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/api/v3/") {
+			fmt.Fprint(w, respond(strings.TrimPrefix(r.URL.RequestURI(), "/api/v3"), nil))
+			return
+		}
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, respond(req.Query, req.Variables))
+	}))
+
+	cache, err := lru.NewARC(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGitHub(srv.URL, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "t"}), cache), srv.Close
+}
+
+func TestProtection(t *testing.T) {
+	const (
+		noRule       = `{"data": {"repository": {"viewerCanAdminister": false, "defaultBranchRef": {"name": "main"}, "ref": {"branchProtectionRule": null}}}}`
+		adminNoRule  = `{"data": {"repository": {"viewerCanAdminister": true, "defaultBranchRef": {"name": "main"}, "ref": {"branchProtectionRule": null}}}}`
+		noRulesets   = `{"data": {"repository": {"rulesets": {"nodes": []}}}}`
+		classicRule  = `{"data": {"repository": {"defaultBranchRef": {"name": "main"}, "ref": {"branchProtectionRule": {"pattern": "main", "requiresApprovingReviews": true, "requiredApprovingReviewCount": 1, "requiresStatusChecks": true, "isAdminEnforced": %t}}}}}`
+		rulesetRules = `{"data": {"repository": {"rulesets": {"nodes": [
+			{"name": "default", "enforcement": "ACTIVE", "target": "BRANCH",
+			 "conditions": {"refName": {"include": ["~DEFAULT_BRANCH"], "exclude": []}},
+			 "rules": {"nodes": [{"type": "PULL_REQUEST", "parameters": {"requiredApprovingReviewCount": 2}}, {"type": "NON_FAST_FORWARD"}, {"type": "DELETION"}]},
+			 "bypassActors": {"totalCount": %d}},
+			{"name": "disabled", "enforcement": "DISABLED", "target": "BRANCH",
+			 "conditions": {"refName": {"include": ["~ALL"], "exclude": []}},
+			 "rules": {"nodes": [{"type": "REQUIRED_SIGNATURES"}]},
+			 "bypassActors": {"totalCount": 0}},
+			{"name": "releases", "enforcement": "ACTIVE", "target": "BRANCH",
+			 "conditions": {"refName": {"include": ["refs/heads/release-*"], "exclude": []}},
+			 "rules": {"nodes": [{"type": "REQUIRED_SIGNATURES"}]},
+			 "bypassActors": {"totalCount": 0}}
+		]}}}}`
+	)
+
+	tests := []struct {
+		name     string
+		rule     string
+		rulesets string
+		want     Protection
+	}{
+		{
+			name:     "unprotected",
+			rule:     noRule,
+			rulesets: noRulesets,
+			want:     Protection{AllowsForcePushes: true, AllowsDeletions: true, AdminsCanBypass: true},
+		},
+		{
+			name:     "unprotected as seen by an admin",
+			rule:     adminNoRule,
+			rulesets: noRulesets,
+			want:     Protection{AllowsForcePushes: true, AllowsDeletions: true, AdminsCanBypass: true, Readable: true},
+		},
+		{
+			name:     "admin enforced rule",
+			rule:     fmt.Sprintf(classicRule, true),
+			rulesets: noRulesets,
+			want:     Protection{RequiredReviews: 1, RequiresStatusChecks: true, Sources: []string{`branch protection rule "main"`}},
+		},
+		{
+			name:     "ruleset without bypass actors",
+			rule:     noRule,
+			rulesets: fmt.Sprintf(rulesetRules, 0),
+			want:     Protection{RequiredReviews: 2, Sources: []string{`ruleset "default"`}},
+		},
+		{
+			name:     "ruleset cannot stop admins bypassing the rule",
+			rule:     fmt.Sprintf(classicRule, false),
+			rulesets: fmt.Sprintf(rulesetRules, 0),
+			want: Protection{RequiredReviews: 2, RequiresStatusChecks: true, AdminsCanBypass: true,
+				Sources: []string{`branch protection rule "main"`, `ruleset "default"`}},
+		},
+		{
+			name:     "admins can bypass the ruleset",
+			rule:     fmt.Sprintf(classicRule, true),
+			rulesets: fmt.Sprintf(rulesetRules, 1),
+			want: Protection{RequiredReviews: 2, RequiresStatusChecks: true, AdminsCanBypass: true,
+				Sources: []string{`branch protection rule "main"`, `ruleset "default"`}},
+		},
+		{
+			name:     "every rule enforced for admins",
+			rule:     fmt.Sprintf(classicRule, true),
+			rulesets: fmt.Sprintf(rulesetRules, 0),
+			want: Protection{RequiredReviews: 2, RequiresStatusChecks: true,
+				Sources: []string{`branch protection rule "main"`, `ruleset "default"`}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g, done := fakeGitHub(t, func(query string, _ map[string]interface{}) string {
+				if strings.Contains(query, "rulesets(") {
+					return tc.rulesets
+				}
+				return tc.rule
+			})
+			defer done()
+
+			p, err := g.Protection(context.Background(), "owner", "repo", "main")
+			if err != nil {
+				t.Fatalf("Protection: %v", err)
+			}
+			if !reflect.DeepEqual(*p, tc.want) {
+				t.Errorf("Protection() = %+v, want %+v", *p, tc.want)
+			}
+		})
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	g, done := fakeGitHub(t, func(string, map[string]interface{}) string { return `{}` })
+	defer done()
+	g.limits.observe(RateLimit{Resource: "graphql", Limit: 5000, Remaining: 0, Reset: time.Now().Add(time.Hour)})
+
+	limited := errors.New("query: API rate limit exceeded for user ID 1.")
+	var rle *RateLimitError
+	if err := g.rateLimited(limited); !errors.As(err, &rle) {
+		t.Errorf("rateLimited(%v) = %v, want a RateLimitError", limited, err)
+	}
+
+	// Unrelated errors are not mistaken for rate limits while the budget is exhausted.
+	notFound := errors.New("query: Could not resolve to a Repository with the name 'owner/repo'.")
+	if err := g.rateLimited(notFound); err != notFound {
+		t.Errorf("rateLimited(%v) = %v, want it unchanged", notFound, err)
+	}
+}
+
+func TestCheckReleaserV2(t *testing.T) {
+	g, done := fakeGitHub(t, func(path string, _ map[string]interface{}) string {
+		if path != "/repos/owner/repo/releases?per_page=10" {
+			return `{"message": "Not Found"}`
+		}
+		// Trimmed from real responses: a release by GitHub Actions, one by a
+		// person, and one whose author was deleted.
+		return `[
+			{"tag_name": "v3", "html_url": "https://github.com/owner/repo/releases/tag/v3", "created_at": "2022-05-01T00:00:00Z",
+			 "author": {"login": "github-actions[bot]", "id": 41898282, "type": "Bot", "site_admin": false},
+			 "assets": [{"name": "sbom.spdx.json", "browser_download_url": "https://github.com/owner/repo/releases/download/v3/sbom.spdx.json"}]},
+			{"tag_name": "v2", "html_url": "https://github.com/owner/repo/releases/tag/v2", "created_at": "2022-04-01T00:00:00Z",
+			 "author": {"login": "alice", "id": 1, "type": "User", "site_admin": false}, "assets": []},
+			{"tag_name": "v1", "html_url": "https://github.com/owner/repo/releases/tag/v1", "created_at": "2022-03-01T00:00:00Z",
+			 "author": null, "assets": []}
+		]`
+	})
+	defer done()
+
+	rs, err := g.Releases(context.Background(), "owner", "repo", 10)
+	if err != nil {
+		t.Fatalf("Releases: %v", err)
+	}
+	if len(rs) != 3 {
+		t.Fatalf("got %d releases, want 3", len(rs))
+	}
+	if got := []bool{rs[0].Automated, rs[1].Automated, rs[2].Automated}; !reflect.DeepEqual(got, []bool{true, false, false}) {
+		t.Errorf("Releases() automated = %v, want only the bot's release automated", got)
+	}
+	if want := []Asset{{Name: "sbom.spdx.json", URL: "https://github.com/owner/repo/releases/download/v3/sbom.spdx.json"}}; !reflect.DeepEqual(rs[0].Assets, want) {
+		t.Errorf("Releases() assets = %+v, want %+v", rs[0].Assets, want)
+	}
+
+	o, err := CheckReleaserV2(context.Background(), &Config{Forge: g, Owner: "owner", Name: "repo"}, Facts{})
+	if err != nil {
+		t.Fatalf("CheckReleaserV2: %v", err)
+	}
+	want := "50.0% of the last 2 releases were likely automated, excluding 1 with an unknown author; cut by humans: v2 by alice"
+	if len(o.Results) != 1 || o.Results[0].Msg != want {
+		t.Errorf("CheckReleaserV2() = %+v, want %q", o.Results, want)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	lru "github.com/hnlq715/golang-lru"
+	"github.com/xanzy/go-gitlab"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/klog/v2"
+)
+
+var gitlabBotRE = regexp.MustCompile(`^(project|group)_\d+_bot`)
+
+// gitlabSignature is a commit signature as returned by GitLab, which reports
+// GPG, X.509 and SSH signatures from the same endpoint.
+type gitlabSignature struct {
+	SignatureType      string `json:"signature_type"`
+	VerificationStatus string `json:"verification_status"`
+	KeyUserName        string `json:"gpg_key_user_name"`
+	X509Certificate    struct {
+		X509Issuer struct {
+			Subject string `json:"subject"`
+		} `json:"x509_issuer"`
+	} `json:"x509_certificate"`
+}
+
+// kind returns the signature type, named as by signatureType.
+func (s *gitlabSignature) kind() string {
+	switch s.SignatureType {
+	case "PGP":
+		return "gpg"
+	case "SSH":
+		return "ssh"
+	case "X509":
+		if strings.Contains(s.X509Certificate.X509Issuer.Subject, "sigstore") {
+			return "gitsign"
+		}
+		return "smime"
+	}
+	return ""
+}
+
+// GitLab is the Forge for a GitLab instance, such as gitlab.com or a self-hosted server.
+type GitLab struct {
+	base   string
+	token  string
+	client *gitlab.Client
+	cache  *lru.ARCCache
+	// commits shares a history fetch between checks that need it at the same time.
+	commits singleflight.Group
+}
+
+func NewGitLab(base string, token string, cache *lru.ARCCache) (*GitLab, error) {
+	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(base+"/api/v4"))
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	return &GitLab{base: base, token: token, client: client, cache: cache}, nil
+}
+
+func (g *GitLab) Host() string {
+	u, err := url.Parse(g.base)
+	if err != nil {
+		return g.base
+	}
+	return u.Host
+}
+
+func (g *GitLab) Commits(ctx context.Context, owner, name, ref string, ho HistoryOptions) ([]Commit, error) {
+	key := asSha256([]interface{}{owner, name, ref, ho})
+	v, err := shared(ctx, &g.commits, key, func(ctx context.Context) (interface{}, error) {
+		return g.listCommits(ctx, owner, name, ref, ho)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cs, _ := v.([]Commit)
+	return cs, nil
+}
+
+func (g *GitLab) listCommits(ctx context.Context, owner, name, ref string, ho HistoryOptions) ([]Commit, error) {
+	pid := owner + "/" + name
+	since := ho.since()
+	opts := &gitlab.ListCommitsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: min(ho.Commits, maxPageSize)},
+		RefName:     gitlab.String(ref),
+		Since:       &since,
+	}
+
+	key := asSha256([]interface{}{g.base, pid, ref, ho})
+	if cached, exist := g.cache.Get(key); exist {
+		return cached.([]Commit), nil
+	}
+
+	cs := []*gitlab.Commit{}
+	for len(cs) < ho.Commits {
+		page, resp, err := g.client.Commits.ListCommits(pid, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			// Unknown refs are reported the same way as they are by GitHub: no commits.
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("list commits: %w", err)
+		}
+		cs = append(cs, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(cs) > ho.Commits {
+		cs = cs[:ho.Commits]
+	}
+
+	// As on GitHub, the newest commit is returned even if it is too old.
+	if len(cs) == 0 {
+		opts.Since = nil
+		opts.Page = 0
+		opts.PerPage = 1
+		page, _, err := g.client.Commits.ListCommits(pid, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("list commits: %w", err)
+		}
+		cs = page
+	}
+
+	usernames := map[string]string{}
+	ret := []Commit{}
+	for _, c := range cs {
+		co := Commit{
+			SHA:       c.ID,
+			Message:   c.Message,
+			Author:    User{Login: g.username(ctx, c.AuthorEmail, usernames)},
+			Committer: User{Login: g.username(ctx, c.CommitterEmail, usernames)},
+		}
+		if c.CommittedDate != nil {
+			co.CommittedDate = *c.CommittedDate
+		}
+
+		sig, resp, err := g.signature(ctx, pid, c.ID)
+		switch {
+		case err == nil:
+			co.Signed = sig.VerificationStatus == "verified"
+			co.SignatureType = sig.kind()
+			co.Signer = sig.KeyUserName
+		case resp != nil && resp.StatusCode == http.StatusNotFound:
+			// unsigned
+		default:
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+
+		mrs, _, err := g.client.Commits.ListMergeRequestsByCommit(pid, c.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("merge requests: %w", err)
+		}
+
+		for _, mr := range mrs {
+			if mr.MergedAt == nil {
+				continue
+			}
+
+			author := ""
+			if mr.Author != nil {
+				author = mr.Author.Username
+			}
+			pr := PullRequest{
+				Number:   mr.IID,
+				MergedAt: *mr.MergedAt,
+				HeadSHA:  mr.SHA,
+				Author:   User{Login: author},
+			}
+			if mr.MergedBy != nil {
+				pr.MergedBy = User{Login: mr.MergedBy.Username}
+			}
+
+			// Merging someone elses merge request is considered tacit approval
+			if mr.MergedBy != nil && mr.MergedBy.Username != author {
+				co.Approved = true
+				co.Reviewed = true
+			}
+
+			approvals, _, err := g.client.MergeRequestApprovals.GetConfiguration(mr.ProjectID, mr.IID, gitlab.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("approvals: %w", err)
+			}
+			for _, a := range approvals.ApprovedBy {
+				if a.User == nil {
+					continue
+				}
+				pr.Reviews = append(pr.Reviews, Review{State: "APPROVED", Author: &User{Login: a.User.Username}})
+				if a.User.Username != author {
+					co.Approved = true
+					co.Reviewed = true
+				}
+			}
+
+			co.AssociatedMergeRequest = pr
+			break
+		}
+
+		ret = append(ret, co)
+	}
+
+	g.cache.Add(key, ret)
+	return ret, nil
+}
+
+// signature fetches the signature of a commit. The client's GetGPGSiganature
+// drops the signature type, so the request is made directly.
+func (g *GitLab) signature(ctx context.Context, pid string, sha string) (*gitlabSignature, *gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s/repository/commits/%s/signature", gitlab.PathEscape(pid), url.PathEscape(sha))
+	req, err := g.client.NewRequest(http.MethodGet, u, nil, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return nil, nil, err
+	}
+	sig := &gitlabSignature{}
+	resp, err := g.client.Do(req, sig)
+	if err != nil {
+		return nil, resp, err
+	}
+	return sig, resp, nil
+}
+
+// username returns the username of the user with the given commit email, so
+// that commits can be compared with merge request authors and approvers. If
+// the email does not belong to exactly one user, the email itself is returned.
+// Lookups are memoized in seen.
+func (g *GitLab) username(ctx context.Context, email string, seen map[string]string) string {
+	if u, ok := seen[email]; ok {
+		return u
+	}
+	seen[email] = email
+	if email == "" {
+		return ""
+	}
+
+	us, _, err := g.client.Users.ListUsers(&gitlab.ListUsersOptions{Search: gitlab.String(email)}, gitlab.WithContext(ctx))
+	if err != nil {
+		klog.V(1).Infof("user search for %s: %v", email, err)
+		return email
+	}
+	if len(us) == 1 {
+		seen[email] = us[0].Username
+	}
+	return seen[email]
+}
+
+func (g *GitLab) Releases(ctx context.Context, owner, name string, n int) ([]Release, error) {
+	pid := owner + "/" + name
+	rs, _, err := g.client.Releases.ListReleases(pid, &gitlab.ListReleasesOptions{PerPage: n}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list releases: %w", err)
+	}
+
+	ret := []Release{}
+	for _, r := range rs {
+		rel := Release{
+			Tag:    r.TagName,
+			Author: r.Author.Username,
+			// Project and group access tokens act as users named project_<id>_bot or group_<id>_bot.
+			Automated: gitlabBotRE.MatchString(r.Author.Username),
+			URL:       fmt.Sprintf("%s/-/releases/%s", g.RepoURL(owner, name), url.PathEscape(r.TagName)),
+		}
+		if r.CreatedAt != nil {
+			rel.CreatedAt = *r.CreatedAt
+		}
+		for _, l := range r.Assets.Links {
+			rel.Assets = append(rel.Assets, Asset{Name: l.Name, URL: l.URL})
+		}
+		ret = append(ret, rel)
+	}
+	return ret, nil
+}
+
+func (g *GitLab) DefaultBranch(ctx context.Context, owner, name string) (string, error) {
+	p, _, err := g.client.Projects.GetProject(owner+"/"+name, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("get project: %w", err)
+	}
+	return p.DefaultBranch, nil
+}
+
+func (g *GitLab) RepoURL(owner, name string) string {
+	return fmt.Sprintf("%s/%s/%s", g.base, owner, name)
+}
+
+func (g *GitLab) CommitURL(owner, name, sha string) string {
+	return fmt.Sprintf("%s/-/commit/%s", g.RepoURL(owner, name), sha)
+}
+
+func (g *GitLab) PullRequestURL(owner, name string, number int) string {
+	return fmt.Sprintf("%s/-/merge_requests/%d", g.RepoURL(owner, name), number)
+}
+
+func (g *GitLab) ReleasesURL(owner, name string) string {
+	return g.RepoURL(owner, name) + "/-/releases"
+}
+
+func (g *GitLab) CloneURL(owner, name string) string {
+	return g.RepoURL(owner, name) + ".git"
+}
+
+func (g *GitLab) CloneAuth(_ context.Context) (transport.AuthMethod, error) {
+	if g.token == "" {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{Username: "oauth2", Password: g.token}, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	lru "github.com/hnlq715/golang-lru"
+)
+
+// fakeGitLab serves the parts of the GitLab API used by GitLab.Commits for the
+// project group/project, whose default branch, main, has two commits.
+func fakeGitLab(t *testing.T) *httptest.Server {
+	t.Helper()
+	responses := map[string]string{
+		"/api/v4/projects/group%2Fproject/repository/commits": `[
+			{"id": "aaaa", "message": "signed and approved", "author_name": "Alice", "author_email": "alice@example.com", "committer_name": "Alice", "committer_email": "alice@example.com", "committed_date": "2022-04-01T00:00:00Z"},
+			{"id": "bbbb", "message": "pushed", "author_name": "Alice", "author_email": "alice@example.com", "committer_name": "Alice", "committer_email": "alice@example.com", "committed_date": "2022-03-01T00:00:00Z"}
+		]`,
+		"/api/v4/projects/group%2Fproject/repository/commits/aaaa/signature":      `{"signature_type": "PGP", "verification_status": "verified", "gpg_key_id": 1, "gpg_key_primary_keyid": "8254AAB3FBD54AC9", "gpg_key_user_name": "alice", "gpg_key_user_email": "alice@example.com", "gpg_key_subkey_id": null, "commit_source": "gitaly"}`,
+		"/api/v4/projects/group%2Fproject/repository/commits/aaaa/merge_requests": `[{"iid": 7, "project_id": 1, "sha": "aaaa", "merged_at": "2022-04-01T00:00:00Z", "author": {"username": "alice"}, "merged_by": {"username": "bob"}}]`,
+		"/api/v4/projects/group%2Fproject/repository/commits/bbbb/merge_requests": `[]`,
+		"/api/v4/projects/1/merge_requests/7/approvals":                           `{"approved_by": [{"user": {"username": "bob"}}]}`,
+		"/api/v4/projects/group%2Fproject":                                        `{"default_branch": "main"}`,
+		"/api/v4/users":                                                           `[{"username": "alice", "public_email": "alice@example.com"}]`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() == "/api/v4/projects/group%2Fproject/repository/commits" && r.URL.Query().Get("ref_name") != "main" {
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		body, ok := responses[r.URL.EscapedPath()]
+		if !ok {
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestGitLabCommits(t *testing.T) {
+	srv := fakeGitLab(t)
+	defer srv.Close()
+
+	cache, err := lru.NewARC(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGitLab(srv.URL, "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := g.Commits(context.Background(), "group", "project", "main", HistoryOptions{Commits: 10})
+	if err != nil {
+		t.Fatalf("Commits: %v", err)
+	}
+	if len(cs) != 2 {
+		t.Fatalf("got %d commits, want 2", len(cs))
+	}
+
+	signed := cs[0]
+	if !signed.Signed || signed.SignatureType != "gpg" || signed.Signer != "alice" {
+		t.Errorf("first commit signature = %v, %q, %q, want verified gpg by alice", signed.Signed, signed.SignatureType, signed.Signer)
+	}
+	if !signed.Approved || !signed.Reviewed {
+		t.Errorf("first commit approved, reviewed = %v, %v, want true, true", signed.Approved, signed.Reviewed)
+	}
+	if signed.Author.Login != "alice" {
+		t.Errorf("first commit author = %q, want the username alice", signed.Author.Login)
+	}
+	if pr := signed.AssociatedMergeRequest; pr.Number != 7 || pr.Author.Login != "alice" || pr.MergedBy.Login != "bob" {
+		t.Errorf("first commit merge request = %+v, want !7 by alice, merged by bob", pr)
+	}
+
+	pushed := cs[1]
+	if pushed.Signed || pushed.SignatureType != "" {
+		t.Errorf("second commit signature = %v, %q, want unsigned", pushed.Signed, pushed.SignatureType)
+	}
+	if pushed.AssociatedMergeRequest.Number != 0 || pushed.Reviewed {
+		t.Errorf("second commit merge request = %+v, reviewed %v, want none", pushed.AssociatedMergeRequest, pushed.Reviewed)
+	}
+}
+
+func TestGitLabSignatureKind(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"signature_type": "PGP", "verification_status": "verified", "gpg_key_user_name": "alice"}`, "gpg"},
+		{`{"signature_type": "SSH", "verification_status": "verified", "key": {"id": 11, "title": "laptop", "usage_type": "auth_and_signing"}}`, "ssh"},
+		{`{"signature_type": "X509", "verification_status": "unverified", "x509_certificate": {"subject": "CN=alice@example.com", "x509_issuer": {"subject": "CN=Example Corp CA,O=Example"}}}`, "smime"},
+		{`{"signature_type": "X509", "verification_status": "unverified", "x509_certificate": {"subject": "", "x509_issuer": {"subject": "CN=sigstore-intermediate,O=sigstore.dev"}}}`, "gitsign"},
+		{`{"verification_status": "verified"}`, ""},
+	}
+	for _, tc := range tests {
+		sig := &gitlabSignature{}
+		if err := json.Unmarshal([]byte(tc.body), sig); err != nil {
+			t.Fatal(err)
+		}
+		if got := sig.kind(); got != tc.want {
+			t.Errorf("kind() of %s = %q, want %q", tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestGitLabCommitsUnknownRef(t *testing.T) {
+	srv := fakeGitLab(t)
+	defer srv.Close()
+
+	cache, err := lru.NewARC(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGitLab(srv.URL, "", cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := g.Commits(context.Background(), "group", "project", "nope", HistoryOptions{Commits: 10})
+	if err != nil {
+		t.Fatalf("Commits: %v", err)
+	}
+	if len(cs) != 0 {
+		t.Errorf("got %d commits for an unknown ref, want none", len(cs))
+	}
+}
+
+func TestGitLabDefaultBranch(t *testing.T) {
+	srv := fakeGitLab(t)
+	defer srv.Close()
+
+	g, err := NewGitLab(srv.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := g.DefaultBranch(context.Background(), "group", "project")
+	if err != nil {
+		t.Fatalf("DefaultBranch: %v", err)
+	}
+	if b != "main" {
+		t.Errorf("DefaultBranch() = %q, want main", b)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	exprRE = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+	// untrustedRE matches expression contexts whose values can be chosen by
+	// whoever opens an issue, pull request, comment or commit.
+	untrustedRE = regexp.MustCompile(`github\.head_ref|github\.event\.(` + strings.Join([]string{
+		`issue\.(title|body)`,
+		`pull_request\.(title|body)`,
+		`pull_request\.head\.(ref|label|repo\.default_branch)`,
+		`(comment|review|review_comment)\.body`,
+		`discussion\.(title|body)`,
+		`pages\.[^.]+\.page_name`,
+		`(head_commit|commits\.[^.]+)\.(message|author\.(email|name))`,
+		`workflow_run\.(head_branch|head_commit\.(message|author\.(email|name))|pull_requests\.[^.]+\.head\.ref)`,
+	}, "|") + `)`)
+
+	// prHeadRE matches expressions that refer to the code of a pull request
+	// rather than the base repository.
+	prHeadRE = regexp.MustCompile(`github\.head_ref|github\.event\.(pull_request\.head\.|workflow_run\.head_|workflow_run\.pull_requests)|refs/pull/`)
+)
+
+// privilegedTriggers run with a write token and access to secrets, even when
+// started from a fork.
+var privilegedTriggers = []string{"pull_request_target", "workflow_run"}
+
+// scriptLine returns the line of the i'th line of a run script. Folded
+// scripts do not keep their line breaks, so the first line is used.
+func scriptLine(n *yaml.Node, i int) int {
+	switch {
+	case n.Style&yaml.LiteralStyle != 0:
+		return n.Line + 1 + i
+	case n.Style&yaml.FoldedStyle != 0:
+		return n.Line + 1
+	}
+	return n.Line
+}
+
+// injections returns the untrusted expressions interpolated into a run script.
+func injections(w *Workflow, j *Job, s *Step) ([]string, []Location) {
+	found := []string{}
+	locs := []Location{}
+	for i, line := range strings.Split(s.Run.Value, "\n") {
+		for _, m := range exprRE.FindAllStringSubmatch(line, -1) {
+			expr := strings.TrimSpace(m[1])
+			if !untrustedRE.MatchString(expr) {
+				continue
+			}
+			found = append(found, fmt.Sprintf("%s interpolates %s", w.where(j, s), expr))
+			locs = append(locs, Location{Path: w.Path, Line: scriptLine(s.Run, i)})
+		}
+	}
+	return found, locs
+}
+
+// untrustedCheckout returns the step that checks out pull request code in a
+// job, and whether any later step runs it.
+func untrustedCheckout(j *Job) (*Step, bool) {
+	for i, s := range j.Steps {
+		if s.Uses == nil || !strings.HasPrefix(s.Uses.Value, "actions/checkout@") {
+			continue
+		}
+		untrusted := false
+		for _, key := range []string{"ref", "repository"} {
+			if v := mapValue(s.With, key); v != nil && prHeadRE.MatchString(v.Value) {
+				untrusted = true
+			}
+		}
+		if !untrusted {
+			continue
+		}
+		for _, later := range j.Steps[i+1:] {
+			if later.Run != nil || (later.Uses != nil && strings.HasPrefix(later.Uses.Value, "./")) {
+				return s, true
+			}
+		}
+		return s, false
+	}
+	return nil, false
+}
+
+// CheckScriptInjection finds workflows that interpolate attacker-controlled
+// event data into shell scripts, and privileged workflows that run code from
+// pull requests.
+func CheckScriptInjection(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
+	ws, o, err := workflowsFrom(in)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	injected := []string{}
+	injectedLocs := []Location{}
+	pwned := []string{}
+	pwnedLocs := []Location{}
+
+	for _, w := range ws {
+		triggers := w.Triggers()
+		privileged := []string{}
+		for _, t := range privilegedTriggers {
+			if triggers[t] {
+				privileged = append(privileged, t)
+			}
+		}
+
+		for _, j := range w.Jobs {
+			for _, s := range j.Steps {
+				if s.Run == nil {
+					continue
+				}
+				found, locs := injections(w, j, s)
+				injected = append(injected, found...)
+				injectedLocs = append(injectedLocs, locs...)
+			}
+
+			if len(privileged) == 0 {
+				continue
+			}
+			if s, runs := untrustedCheckout(j); runs {
+				pwned = append(pwned, fmt.Sprintf("%s runs pull request code on %s", w.where(j, s), strings.Join(privileged, ", ")))
+				pwnedLocs = append(pwnedLocs, Location{Path: w.Path, Line: s.Line})
+			}
+		}
+	}
+
+	res := []Result{}
+	if len(injected) == 0 {
+		res = append(res, Result{Msg: "No untrusted event data is interpolated into run steps", Score: 0, Max: 10, Level: 1})
+	} else {
+		res = append(res, Result{
+			Msg:       fmt.Sprintf("Found %d untrusted expressions in run steps: %s", len(injected), listed(injected)),
+			Score:     10,
+			Max:       10,
+			Level:     1,
+			Locations: injectedLocs,
+		})
+	}
+
+	if len(pwned) == 0 {
+		res = append(res, Result{Msg: "No privileged workflows check out and run pull request code", Score: 0, Max: 10, Level: 1})
+	} else {
+		res = append(res, Result{
+			Msg:       fmt.Sprintf("%d privileged jobs check out and run pull request code: %s", len(pwned), listed(pwned)),
+			Score:     10,
+			Max:       10,
+			Level:     1,
+			Locations: pwnedLocs,
+		})
+	}
+
+	return &Outcome{Results: res}, nil
+}
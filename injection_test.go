@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInjections(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/triage.yml", []byte(`on: issues
+jobs:
+  triage:
+    steps:
+      - name: Greet
+        run: |
+          echo "Thanks for opening this issue"
+          echo "${{ github.event.issue.title }}" ${{ github.sha }}
+      - run: echo ${{github.head_ref}}
+      - run: echo "${{ github.event.issue.number }} ${{ github.event.pull_request.head.sha }}"
+      - run: >
+          echo
+          ${{ github.event.comment.body }}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := w.Jobs[0]
+	tests := []struct {
+		step      int
+		wantExprs []string
+		wantLines []int
+	}{
+		{step: 0, wantExprs: []string{`.github/workflows/triage.yml job triage step "Greet" interpolates github.event.issue.title`}, wantLines: []int{8}},
+		{step: 1, wantExprs: []string{".github/workflows/triage.yml job triage step #2 interpolates github.head_ref"}, wantLines: []int{9}},
+		// Numbers and SHAs cannot carry a payload.
+		{step: 2, wantExprs: []string{}, wantLines: nil},
+		// Folded scripts are reported at their first line.
+		{step: 3, wantExprs: []string{".github/workflows/triage.yml job triage step #4 interpolates github.event.comment.body"}, wantLines: []int{12}},
+	}
+
+	for _, tc := range tests {
+		found, locs := injections(w, j, j.Steps[tc.step])
+		if !reflect.DeepEqual(found, tc.wantExprs) {
+			t.Errorf("step %d: injections() = %q, want %q", tc.step, found, tc.wantExprs)
+		}
+		lines := []int(nil)
+		for _, l := range locs {
+			lines = append(lines, l.Line)
+		}
+		if !reflect.DeepEqual(lines, tc.wantLines) {
+			t.Errorf("step %d: lines = %v, want %v", tc.step, lines, tc.wantLines)
+		}
+	}
+}
+
+func TestUntrustedCheckout(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow string
+		wantStep int
+		wantRuns bool
+	}{
+		{
+			name: "pull request head is built",
+			workflow: `jobs:
+  test:
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.event.pull_request.head.sha }}
+      - run: make test
+`,
+			wantStep: 1,
+			wantRuns: true,
+		},
+		{
+			name: "workflow run head runs a local action",
+			workflow: `jobs:
+  test:
+    steps:
+      - run: echo start
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.event.workflow_run.head_sha }}
+      - uses: ./.github/actions/build
+`,
+			wantStep: 2,
+			wantRuns: true,
+		},
+		{
+			name: "pull request head is only read",
+			workflow: `jobs:
+  label:
+    steps:
+      - uses: actions/checkout@v3
+        with:
+          repository: ${{ github.event.pull_request.head.repo.full_name }}
+      - uses: actions/labeler@v4
+`,
+			wantStep: 1,
+			wantRuns: false,
+		},
+		{
+			name: "base branch is built",
+			workflow: `jobs:
+  test:
+    steps:
+      - uses: actions/checkout@v3
+      - run: make test
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := parseWorkflow("wf.yml", []byte(tc.workflow))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, runs := untrustedCheckout(w.Jobs[0])
+			got := 0
+			if s != nil {
+				got = s.Index
+			}
+			if got != tc.wantStep || runs != tc.wantRuns {
+				t.Errorf("untrustedCheckout() = step %d, %v, want step %d, %v", got, runs, tc.wantStep, tc.wantRuns)
+			}
+		})
+	}
+}
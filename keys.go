@@ -4,49 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	shhgit "github.com/eth0izzle/shhgit/core"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
 )
 
-func CheckPrivateKeys(ctx context.Context, c *Config) ([]Result, error) {
-	if c.Branch == "unknown" {
-		return []Result{{Msg: "unknown branch"}}, nil
-	}
-
-	cd, err := os.UserCacheDir()
+func CheckPrivateKeys(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	dest, err := checkout(ctx, c)
 	if err != nil {
-		return nil, fmt.Errorf("cache dir: %w", err)
+		return nil, err
 	}
-
-	dest := filepath.Join(cd, "yoloc", c.Owner, c.Name)
-	if err := os.MkdirAll(dest, 0o700); err != nil {
-		return nil, fmt.Errorf("cache dir: %w", err)
-	}
-
-	if _, err := os.Stat(filepath.Join(dest, ".git")); err == nil {
-		_, err := git.PlainOpen(dest)
-		if err != nil {
-			return nil, fmt.Errorf("clone: %w", err)
-		}
-	} else {
-		opts := &git.CloneOptions{
-			URL:               fmt.Sprintf("https://github.com/%s.git", c.Github),
-			SingleBranch:      true,
-			Depth:             1,
-			RecurseSubmodules: git.NoRecurseSubmodules,
-			ReferenceName:     plumbing.NewBranchReferenceName(c.Branch),
-		}
-
-		if _, err := git.PlainCloneContext(ctx, dest, false, opts); err != nil {
-			opts.ReferenceName = plumbing.NewBranchReferenceName("master")
-			if _, err := git.PlainCloneContext(ctx, dest, false, opts); err != nil {
-				return nil, fmt.Errorf("clone: %w", err)
-			}
-		}
+	if dest == "" {
+		return &Outcome{Results: []Result{{Msg: "unknown ref"}}}, nil
 	}
 
 	res := Result{
@@ -62,6 +31,7 @@ func CheckPrivateKeys(ctx context.Context, c *Config) ([]Result, error) {
 	}
 
 	keys := []string{}
+	locs := []Location{}
 	images := map[string]bool{}
 
 	for _, f := range found {
@@ -74,42 +44,44 @@ func CheckPrivateKeys(ctx context.Context, c *Config) ([]Result, error) {
 
 		if f.kind == "key" && !strings.Contains(f.path, "test") {
 			keys = append(keys, f.path)
+			locs = append(locs, Location{Path: f.path})
 		}
 	}
 
-	//	log.Printf("possible images: %v", images)
 	if len(keys) > 0 {
 		res = Result{
-			Score: 10,
-			Max:   10,
-			Msg:   fmt.Sprintf("Found %d possibly private key(s): %v", len(keys), keys),
-			Level: 2,
+			Score:     10,
+			Max:       10,
+			Msg:       fmt.Sprintf("Found %d possibly private key(s): %v", len(keys), keys),
+			Level:     2,
+			Locations: locs,
 		}
 	}
 
+	facts := Facts{}
 	for i := range images {
-		c.FoundImages = append(c.FoundImages, i)
+		facts.Images = append(facts.Images, i)
 		// Add some variations
 		if !strings.Contains(i, fmt.Sprintf("%s/%s", c.Name, c.Name)) {
-			c.FoundImages = append(c.FoundImages, i+"/"+c.Name)
+			facts.Images = append(facts.Images, i+"/"+c.Name)
 		}
 		try := fmt.Sprintf("%s-server", c.Name)
 		if !strings.Contains(i, try) {
-			c.FoundImages = append(c.FoundImages, i+"/"+try)
+			facts.Images = append(facts.Images, i+"/"+try)
 		}
 
 		try = fmt.Sprintf("%s-cli", c.Name)
 		if !strings.Contains(i, try) {
-			c.FoundImages = append(c.FoundImages, i+"/"+try)
+			facts.Images = append(facts.Images, i+"/"+try)
 		}
 
 		try = fmt.Sprintf("%s-client", c.Name)
 		if !strings.Contains(i, try) {
-			c.FoundImages = append(c.FoundImages, i+"/"+try)
+			facts.Images = append(facts.Images, i+"/"+try)
 		}
 	}
 
-	return []Result{res}, nil
+	return &Outcome{Results: []Result{res}, Facts: facts}, nil
 }
 
 type match struct {
@@ -2,35 +2,63 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"reflect"
-	"runtime"
+	"regexp"
 	"runtime/debug"
-	"strings"
 	"time"
 
 	"github.com/common-nighthawk/go-figure"
 	lru "github.com/hnlq715/golang-lru"
 	au "github.com/logrusorgru/aurora"
-	"github.com/shurcooL/githubv4"
-	"golang.org/x/oauth2"
 	"k8s.io/klog/v2"
 )
 
 var (
-	repoFlag    = flag.String("repo", "chainguard-dev/yoloc", "Github repo to check")
+	repoFlag    = flag.String("repo", "chainguard-dev/yoloc", "repo to check, as owner/name or a GitHub or GitLab URL")
 	imageFlag   = flag.String("image", "", "image to check")
 	serveFlag   = flag.Bool("serve", false, "yoloc webserver mode")
 	portFlag    = flag.Int("port", 8080, "serve yoloc on this port")
 	persistFlag = flag.String("persist", "", "persistence layer to use (local, firestore, none)")
 	shhgitFlag  = flag.String("shhgit-config", "shhgit.yaml", "path to shhgit config")
+	checksFlag  = flag.String("checks", "", "comma-separated list of check IDs to run (default: all)")
+	skipFlag    = flag.String("skip-checks", "", "comma-separated list of check IDs to skip")
+	listFlag    = flag.Bool("list-checks", false, "list available checks and exit")
+	formatFlag  = flag.String("format", "text", "output format (text, json, sarif)")
+	forgeFlag   = flag.String("forge", "github", "forge hosting repos given as owner/name (github, gitlab)")
+	githubFlag  = flag.String("github-url", defaultGitHubURL, "base URL of the GitHub instance to use, for GitHub Enterprise Server")
+
+	appIDFlag           = flag.Int64("github-app-id", 0, "authenticate as this GitHub App instead of using GITHUB_TOKEN")
+	appKeyFlag          = flag.String("github-app-key", "", "path to the PEM-encoded private key of the GitHub App")
+	appInstallationFlag = flag.Int64("github-app-installation", 0, "GitHub App installation ID (default: discovered for each repository owner)")
+	gitlabFlag          = flag.String("gitlab-url", "https://gitlab.com", "base URL of the GitLab instance to use")
+	pathFlag            = flag.String("path", "", "scan a local git checkout instead of a GitHub repo (network checks are skipped)")
+	refFlag             = flag.String("ref", "", "branch, tag or commit SHA to analyze (default: the repository's default branch)")
+
+	commitsFlag     = flag.Int("commits", defaultHistoryCommits, "maximum number of recent commits to analyze")
+	sinceFlag       = flag.String("since", "", "only analyze commits made on or after this date, as YYYY-MM-DD (default: one year ago)")
+	prPageFlag      = flag.Int("pr-page-size", defaultPullRequests, "number of pull requests to fetch for each commit")
+	reviewsPageFlag = flag.Int("review-page-size", defaultReviews, "number of reviews to fetch for each pull request")
+
+	minLevelFlag   = flag.Int("min-level", -4, "exit with a policy failure if the YOLO compliance level is below this (0 to -4)")
+	maxPercentFlag = flag.Int("max-score-percent", 100, "exit with a policy failure if the YOLO score percentage is above this")
+	requireFlag    = flag.String("require-check", "", "comma-separated list of check IDs that must run cleanly and score zero")
+
+	batchFlag       = flag.String("batch", "", "scan the targets listed in this file (- for stdin), one repo [image] or JSON object per line")
+	concurrencyFlag = flag.Int("concurrency", 4, "number of repositories to scan at once in batch and org mode")
+
+	orgFlag           = flag.String("org", "", "scan every repository in this GitHub organization or user account")
+	orgArchivedFlag   = flag.Bool("org-include-archived", false, "include archived repositories in org mode")
+	orgForksFlag      = flag.Bool("org-include-forks", false, "include forks in org mode")
+	orgMatchFlag      = flag.String("org-match", "", "only scan org repositories whose name matches this regular expression")
+	orgActiveDaysFlag = flag.Int("org-active-days", 0, "only scan org repositories pushed to within this many days (0 for any)")
 )
 
 type (
-	Checker   func(context.Context, *Config) ([]Result, error)
+	Checker   func(context.Context, *Config, Facts) (*Outcome, error)
 	Colorizer func(arc interface{}) au.Value
 )
 
@@ -42,10 +70,6 @@ func checkBox(w io.Writer, c Colorizer, mark string, msg string) {
 		c(msg))
 }
 
-func fname(i interface{}) string {
-	return strings.Replace(runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name(), "main.", "", 1)
-}
-
 func personality(w io.Writer, perc int) {
 	fig := ""
 	desc := ""
@@ -115,87 +139,81 @@ func printResult(w io.Writer, n string, r Result, err error) {
 	}
 }
 
-func runChecks(ctx context.Context, w io.Writer, cf *Config) int {
-	if !strings.Contains(cf.Github, "/") {
-		return -1
-	}
-
-	score := 0
-	maxScore := 0
-	cf.Github = strings.Replace(cf.Github, "https://github.com/", "", 1)
-	parts := strings.Split(cf.Github, "/")
-	cf.Owner = parts[0]
-	cf.Name = parts[1]
+func runChecks(ctx context.Context, cf *Config) (*Report, error) {
+	if cf.Path != "" {
+		if err := openLocal(cf); err != nil {
+			return nil, err
+		}
+	} else {
+		f, owner, name, err := cf.Forges.Resolve(ctx, cf.Github)
+		if err != nil {
+			return nil, err
+		}
+		cf.Forge = f
+		cf.Owner = owner
+		cf.Name = name
+		cf.Github = fmt.Sprintf("%s/%s", owner, name)
 
-	fmt.Fprintf(w, "Analyzing %s %s ...\n\n", cf.Github, cf.Image)
+		cf.DefaultBranch, err = f.DefaultBranch(ctx, owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("default branch: %w", err)
+		}
+		if cf.Ref == "" {
+			cf.Ref = cf.DefaultBranch
+		}
+	}
 
-	checkers := []Checker{
-		CheckSBOM,
-		CheckReleaserV2,
-		CheckCommits,
-		CheckPrivateKeys,
-		CheckSignedImage,
+	defs, err := selectChecks(cf.Checks, cf.SkipChecks)
+	if err != nil {
+		return nil, err
 	}
 
-	maxLevel := 0
+	return newReport(cf, schedule(ctx, cf, defs)), nil
+}
 
-	for _, c := range checkers {
-		n := fname(c)
-		key := fmt.Sprintf("%s@%s", cf.Github, n)
+func printReport(w io.Writer, rep *Report) {
+	target := rep.Repo
+	if rep.Path != "" {
+		target = rep.Path
+	}
+	fmt.Fprintf(w, "Analyzing %s %s ...\n\n", target, rep.Image)
 
-		rs, err := cf.Persist.Get(ctx, key)
-		if err != nil || rs == nil {
-			if err != nil {
-				klog.Errorf("get err: %v", err)
-			}
-			rs, err = c(ctx, cf)
-			if err == nil {
-				if err := cf.Persist.Set(ctx, key, rs); err != nil {
-					klog.Errorf("set err: %v", err)
-				}
-			} else {
-				if _, ok := cf.Persist.(*NullPersister); !ok {
-					klog.Errorf("not caching error: %v", err)
-				}
-			}
+	for _, c := range rep.Checks {
+		if c.RateLimitedUntil != nil {
+			checkBox(w, au.BrightYellow, "limit", fmt.Sprintf("%s %s", c.ID, c.Error))
+			continue
 		}
-
-		if err != nil {
-			printResult(w, n, Result{}, err)
+		if c.Error != "" {
+			printResult(w, c.ID, Result{}, errors.New(c.Error))
+			continue
+		}
+		if c.Skipped != "" {
+			checkBox(w, au.BrightBlack, " skip", fmt.Sprintf("%s skipped: %s", c.ID, c.Skipped))
 			continue
 		}
 
-		for _, r := range rs {
+		for _, r := range c.Results {
+			// Results that cannot score are informational.
 			if r.Max == 0 {
+				if r.Msg != "" {
+					checkBox(w, au.BrightBlack, " info", fmt.Sprintf("%s: %s", c.ID, r.Msg))
+				}
 				continue
 			}
-			score += r.Score
-			maxScore += r.Max
-			// For fun, we assign your level to be the highest observed
-			if r.Score > 0 && r.Level > maxLevel {
-				maxLevel = r.Level
-			}
-			printResult(w, n, r, err)
+			printResult(w, c.ID, r, nil)
 		}
 	}
 
-	perc := 0
-	if score > 0 {
-		perc = int((float64(score) / float64(maxScore)) * 100)
-	}
-
-	fmt.Fprintf(w, "\nYour YOLO score: %d out of %d (%d%%)\n", score, maxScore, perc)
-	personality(w, perc)
+	fmt.Fprintf(w, "\nYour YOLO score: %d out of %d (%d%%)\n", rep.Score, rep.MaxScore, rep.Percent)
+	personality(w, rep.Percent)
 
-	fmt.Fprintf(w, "\nYour YOLO compliance level: %d\n", -maxLevel)
+	fmt.Fprintf(w, "\nYour YOLO compliance level: %d\n", -rep.Level)
 
-	badge(w, maxLevel)
-
-	level := (perc / 100) * 4
-	return level
+	badge(w, rep.Level)
 }
 
-func showBanner(w io.Writer) {
+// yolocVersion returns the yoloc version, including the VCS revision it was built from.
+func yolocVersion() string {
 	commit := "unknown"
 	bi, ok := debug.ReadBuildInfo()
 	if ok {
@@ -205,29 +223,88 @@ func showBanner(w io.Writer) {
 			}
 		}
 	}
+	return fmt.Sprintf("v0.1-%7.7s", commit)
+}
 
+func showBanner(w io.Writer) {
 	fmt.Fprintln(w, au.BrightGreen(fmt.Sprintf(`
              |
    |  |  _ \ |  _ \  _|
-  \_, |\___/_|\___/\__|        v0.1-%7.7s
+  \_, |\___/_|\___/\__|        %s
   ___/
-`, commit)))
+`, yolocVersion())))
 }
 
 func main() {
 	flag.Parse()
-	showBanner(os.Stdout)
+	if *listFlag {
+		listChecks(os.Stdout)
+		os.Exit(0)
+	}
+	if *forgeFlag != "github" && *forgeFlag != "gitlab" {
+		klog.Errorf("unknown forge: %q", *forgeFlag)
+		os.Exit(exitFailed)
+	}
+
+	switch *formatFlag {
+	case "text":
+		showBanner(os.Stdout)
+	case "json", "sarif":
+	default:
+		klog.Errorf("unknown format: %q", *formatFlag)
+		os.Exit(exitFailed)
+	}
+
+	policy := &Policy{
+		MinLevel:   *minLevelFlag,
+		MaxPercent: *maxPercentFlag,
+		Require:    splitList(*requireFlag),
+	}
+	for _, id := range policy.Require {
+		if _, ok := registry[id]; !ok {
+			klog.Errorf("unknown required check: %q", id)
+			os.Exit(exitFailed)
+		}
+	}
+
+	history := HistoryOptions{Commits: *commitsFlag, PullRequests: *prPageFlag, Reviews: *reviewsPageFlag}
+	if history.Commits < 1 || history.PullRequests < 1 || history.Reviews < 1 {
+		klog.Errorf("--commits, --pr-page-size and --review-page-size must be positive")
+		os.Exit(exitFailed)
+	}
+	if *sinceFlag != "" {
+		t, err := time.Parse("2006-01-02", *sinceFlag)
+		if err != nil {
+			klog.Errorf("since: %v", err)
+			os.Exit(exitFailed)
+		}
+		history.Since = t
+	}
 
 	ctx := context.Background()
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
-	)
-	httpClient := oauth2.NewClient(context.Background(), src)
-	v4c := githubv4.NewClient(httpClient)
+	var creds Credentials = NewTokenCredentials(os.Getenv("GITHUB_TOKEN"))
+	if *appIDFlag != 0 {
+		ac, err := NewAppCredentials(*githubFlag, *appIDFlag, *appKeyFlag, *appInstallationFlag)
+		if err != nil {
+			klog.Errorf("github app: %v", err)
+			os.Exit(exitFailed)
+		}
+		creds = ac
+	}
+
 	l, _ := lru.NewARCWithExpire(1024, 4*time.Hour)
+	forges := &Forges{
+		Default:     *forgeFlag,
+		GitHubURL:   *githubFlag,
+		Credentials: creds,
+		GitLabURL:   *gitlabFlag,
+		GitLabToken: os.Getenv("GITLAB_TOKEN"),
+		Cache:       l,
+	}
 	persist, err := NewPersist(ctx, *persistFlag)
 	if err != nil {
-		klog.Fatalf("persist: %v", err)
+		klog.Errorf("persist: %v", err)
+		os.Exit(exitFailed)
 	}
 
 	if *serveFlag {
@@ -236,17 +313,79 @@ func main() {
 			addr = fmt.Sprintf(":%d", *portFlag)
 		}
 
-		serve(ctx, &ServerConfig{Addr: addr, V4Client: v4c, Cache: l, Persist: persist})
+		serve(ctx, &ServerConfig{Addr: addr, Forges: forges, Cache: l, Persist: persist, History: history})
 	}
 
 	cf := &Config{
-		Github:   *repoFlag,
-		Image:    *imageFlag,
-		V4Client: v4c,
-		Cache:    l,
-		Persist:  persist,
+		Github:     *repoFlag,
+		Path:       *pathFlag,
+		Image:      *imageFlag,
+		Ref:        *refFlag,
+		History:    history,
+		Forges:     forges,
+		Cache:      l,
+		Persist:    persist,
+		Checks:     splitList(*checksFlag),
+		SkipChecks: splitList(*skipFlag),
 	}
 
-	level := runChecks(ctx, os.Stdout, cf)
-	os.Exit(level)
+	if *pathFlag != "" && *refFlag != "" {
+		klog.Errorf("--ref cannot be used with --path: check out the ref instead")
+		os.Exit(exitFailed)
+	}
+
+	if *pathFlag != "" && (*batchFlag != "" || *orgFlag != "") {
+		klog.Errorf("--path cannot be used with --batch or --org: it scans a single local checkout")
+		os.Exit(exitFailed)
+	}
+
+	if (*batchFlag != "" || *orgFlag != "") && *formatFlag == "sarif" {
+		klog.Errorf("sarif output is not supported in batch or org mode")
+		os.Exit(exitFailed)
+	}
+
+	if *batchFlag != "" {
+		os.Exit(batchMain(ctx, cf, policy, *batchFlag, *concurrencyFlag))
+	}
+
+	if *orgFlag != "" {
+		f := OrgFilter{Archived: *orgArchivedFlag, Forks: *orgForksFlag}
+		if *orgMatchFlag != "" {
+			re, err := regexp.Compile(*orgMatchFlag)
+			if err != nil {
+				klog.Errorf("org-match: %v", err)
+				os.Exit(exitFailed)
+			}
+			f.Match = re
+		}
+		if *orgActiveDaysFlag > 0 {
+			f.ActiveSince = time.Now().Add(-time.Duration(*orgActiveDaysFlag) * 24 * time.Hour)
+		}
+		os.Exit(orgMain(ctx, cf, policy, *orgFlag, f, *concurrencyFlag))
+	}
+
+	rep, err := runChecks(ctx, cf)
+	if err != nil {
+		klog.Errorf("run checks: %v", err)
+		os.Exit(exitFailed)
+	}
+
+	switch *formatFlag {
+	case "json":
+		err = writeJSON(os.Stdout, rep)
+	case "sarif":
+		err = writeSARIF(os.Stdout, rep)
+	default:
+		printReport(os.Stdout, rep)
+	}
+	if err != nil {
+		klog.Errorf("write report: %v", err)
+		os.Exit(exitFailed)
+	}
+
+	violations := policy.Violations(rep)
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "policy violation: %s\n", v)
+	}
+	os.Exit(exitCode(rep, violations))
 }
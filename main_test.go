@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunChecksRef(t *testing.T) {
+	g, done := fakeGitHub(t, func(string, map[string]interface{}) string {
+		return `{"data": {"repository": {"defaultBranchRef": {"name": "trunk"}}}}`
+	})
+	defer done()
+
+	var got *Config
+	withRegistry(t, &CheckDef{ID: "record", Run: func(_ context.Context, c *Config, _ Facts) (*Outcome, error) {
+		got = c
+		return &Outcome{}, nil
+	}})
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "", want: "trunk"},
+		{ref: "v1.0.0", want: "v1.0.0"},
+	}
+	for _, tc := range tests {
+		cf := &Config{
+			Github:  g.base + "/owner/repo",
+			Ref:     tc.ref,
+			Forges:  &Forges{GitHubURL: g.base, GitLabURL: "https://gitlab.com", Credentials: NewTokenCredentials("")},
+			Persist: &NullPersister{},
+		}
+		rep, err := runChecks(context.Background(), cf)
+		if err != nil {
+			t.Fatalf("runChecks(ref %q): %v", tc.ref, err)
+		}
+		if got.DefaultBranch != "trunk" || got.Ref != tc.want || rep.Ref != tc.want {
+			t.Errorf("runChecks(ref %q) default branch, ref, report ref = %q, %q, %q, want trunk, %q, %q", tc.ref, got.DefaultBranch, got.Ref, rep.Ref, tc.want, tc.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"k8s.io/klog/v2"
+)
+
+// worstOffenders is how many repositories are singled out in an org report.
+const worstOffenders = 10
+
+type orgReposQuery struct {
+	RepositoryOwner struct {
+		Repositories struct {
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage bool
+			}
+			Nodes []struct {
+				NameWithOwner githubv4.String
+				IsArchived    bool
+				IsFork        bool
+				PushedAt      githubv4.DateTime
+			}
+			// Without ownerAffiliations, the repositories of a user include
+			// those they collaborate on.
+		} `graphql:"repositories(first: 100, after: $cursor, ownerAffiliations: [OWNER], orderBy: {field: PUSHED_AT, direction: DESC})"`
+	} `graphql:"repositoryOwner(login: $login)"`
+}
+
+// OrgFilter decides which repositories of an organization are scanned.
+type OrgFilter struct {
+	Archived bool
+	Forks    bool
+	// Match, if set, must match the repository name.
+	Match *regexp.Regexp
+	// ActiveSince, if set, skips repositories that were last pushed to before it.
+	ActiveSince time.Time
+}
+
+// OrgRepos lists the repositories of an organization or user that pass the filter.
+func OrgRepos(ctx context.Context, client *githubv4.Client, login string, f OrgFilter) ([]string, error) {
+	query := &orgReposQuery{}
+	vars := map[string]interface{}{
+		"login":  githubv4.String(login),
+		"cursor": (*githubv4.String)(nil),
+	}
+
+	repos := []string{}
+	for {
+		if err := client.Query(ctx, query, vars); err != nil {
+			return nil, fmt.Errorf("query: %w", err)
+		}
+
+		rs := query.RepositoryOwner.Repositories
+		for _, r := range rs.Nodes {
+			name := string(r.NameWithOwner)
+			switch {
+			case r.IsArchived && !f.Archived:
+				klog.V(1).Infof("skipping archived repo %s", name)
+			case r.IsFork && !f.Forks:
+				klog.V(1).Infof("skipping fork %s", name)
+			case f.Match != nil && !f.Match.MatchString(strings.TrimPrefix(name, login+"/")):
+				klog.V(1).Infof("skipping unmatched repo %s", name)
+			case r.PushedAt.Before(f.ActiveSince):
+				klog.V(1).Infof("skipping inactive repo %s (last push %s)", name, r.PushedAt)
+			default:
+				repos = append(repos, name)
+			}
+		}
+
+		if !rs.PageInfo.HasNextPage {
+			break
+		}
+		vars["cursor"] = githubv4.NewString(rs.PageInfo.EndCursor)
+	}
+
+	return repos, nil
+}
+
+// CheckRate is how often a check failed across an organization.
+type CheckRate struct {
+	ID string `json:"id"`
+	// Failed is the number of repositories where the check scored YOLO points.
+	Failed int `json:"failed"`
+	// Errored is the number of repositories where the check could not run.
+	Errored int `json:"errored"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// OrgReport rolls up the results for every scanned repository in an organization.
+type OrgReport struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Org           string `json:"org"`
+	Scanned       int    `json:"scanned"`
+	Failed        int    `json:"failed"`
+	// Levels maps each YOLO level to the number of repositories at that level.
+	Levels         map[int]int    `json:"levels"`
+	WorstOffenders []string       `json:"worstOffenders"`
+	CheckRates     []CheckRate    `json:"checkRates"`
+	Results        []*BatchResult `json:"results"`
+}
+
+// newOrgReport summarizes ranked batch results.
+func newOrgReport(org string, results []*BatchResult) *OrgReport {
+	or := &OrgReport{
+		SchemaVersion:  reportSchemaVersion,
+		Org:            org,
+		Levels:         map[int]int{},
+		WorstOffenders: []string{},
+		CheckRates:     []CheckRate{},
+		Results:        results,
+	}
+
+	rates := map[string]*CheckRate{}
+	ids := []string{}
+	for _, br := range results {
+		if br.Report == nil {
+			or.Failed++
+			continue
+		}
+
+		or.Scanned++
+		or.Levels[br.Report.Level]++
+		if len(or.WorstOffenders) < worstOffenders && br.Report.Score > 0 {
+			or.WorstOffenders = append(or.WorstOffenders, br.Repo)
+		}
+
+		for _, c := range br.Report.Checks {
+			if c.Skipped != "" {
+				continue
+			}
+			cr, ok := rates[c.ID]
+			if !ok {
+				cr = &CheckRate{ID: c.ID}
+				rates[c.ID] = cr
+				ids = append(ids, c.ID)
+			}
+			cr.Total++
+			if c.Error != "" {
+				cr.Errored++
+				continue
+			}
+			for _, r := range c.Results {
+				if r.Score > 0 {
+					cr.Failed++
+					break
+				}
+			}
+		}
+	}
+
+	for _, id := range ids {
+		cr := rates[id]
+		if ran := cr.Total - cr.Errored; ran > 0 {
+			cr.Percent = cr.Failed * 100 / ran
+		}
+		or.CheckRates = append(or.CheckRates, *cr)
+	}
+	sort.SliceStable(or.CheckRates, func(i, j int) bool {
+		return or.CheckRates[i].Percent > or.CheckRates[j].Percent
+	})
+
+	return or
+}
+
+func printOrgReport(w io.Writer, or *OrgReport) {
+	fmt.Fprintf(w, "YOLO report for %s: %d repositories scanned, %d failed\n\n", or.Org, or.Scanned, or.Failed)
+
+	fmt.Fprintln(w, "YOLO compliance levels:")
+	for level := 0; level <= 4; level++ {
+		fmt.Fprintf(w, "  %2d: %d\n", -level, or.Levels[level])
+	}
+
+	fmt.Fprintln(w, "\nWorst offenders:")
+	for i, repo := range or.WorstOffenders {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, repo)
+	}
+
+	fmt.Fprintln(w, "\nCheck failure rates:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  CHECK\tFAILED\tERRORED\tRATE")
+	for _, cr := range or.CheckRates {
+		fmt.Fprintf(tw, "  %s\t%d/%d\t%d\t%d%%\n", cr.ID, cr.Failed, cr.Total-cr.Errored, cr.Errored, cr.Percent)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	printLeaderboard(w, or.Results)
+}
+
+// orgMain scans every matching repository in an organization and returns the exit code.
+func orgMain(ctx context.Context, base *Config, policy *Policy, org string, f OrgFilter, concurrency int) int {
+	client, err := base.Forges.V4Client(ctx, org)
+	if err != nil {
+		klog.Errorf("github client for %s: %v", org, err)
+		return exitFailed
+	}
+
+	repos, err := OrgRepos(ctx, client, org, f)
+	if err != nil {
+		klog.Errorf("list repos for %s: %v", org, err)
+		return exitFailed
+	}
+	klog.Infof("found %d repositories in %s", len(repos), org)
+
+	ts := []Target{}
+	for _, r := range repos {
+		ts = append(ts, Target{Repo: r})
+	}
+
+	results := runBatch(ctx, base, ts, concurrency)
+	rankResults(results)
+	or := newOrgReport(org, results)
+
+	switch *formatFlag {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(or)
+	default:
+		printOrgReport(os.Stdout, or)
+	}
+	if err != nil {
+		klog.Errorf("write report: %v", err)
+		return exitFailed
+	}
+
+	return batchExitCode(results, policy)
+}
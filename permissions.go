@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scopeUsers match the steps that need write access to a GITHUB_TOKEN scope.
+// Scopes without an entry are never reported as unneeded.
+var scopeUsers = map[string]*regexp.Regexp{
+	"contents":        regexp.MustCompile(`git push|gh release|goreleaser|action-gh-release|release-action|create-release|git-auto-commit|create-pull-request|release-please|semantic-release`),
+	"packages":        regexp.MustCompile(`ghcr\.io|docker/login-action|docker push|\bko\s+(build|publish|resolve|apply)\b|goreleaser`),
+	"id-token":        regexp.MustCompile(`cosign|sigstore|configure-aws-credentials|google-github-actions/auth|azure/login|gh-action-pypi-publish|--provenance|slsa-framework|actions/attest|goreleaser|\bko\s+(build|publish|resolve|apply)\b`),
+	"pull-requests":   regexp.MustCompile(`gh pr|create-pull-request|github-script|pull-request-comment|actions/labeler|release-please|dependabot/fetch-metadata`),
+	"issues":          regexp.MustCompile(`gh issue|github-script|actions/stale|actions/labeler|create-issue`),
+	"security-events": regexp.MustCompile(`codeql-action|upload-sarif`),
+	"pages":           regexp.MustCompile(`deploy-pages`),
+	"attestations":    regexp.MustCompile(`actions/attest`),
+}
+
+// tokenScopes is the effective GITHUB_TOKEN scope of a job.
+type tokenScopes struct {
+	// Default is set when no permissions block applies, so the repository or
+	// organization default is used.
+	Default bool
+	// WriteAll is set for write-all, ReadAll for read-all.
+	WriteAll bool
+	ReadAll  bool
+	// Scopes maps scope names to "read", "write" or "none".
+	Scopes map[string]string
+	Line   int
+}
+
+func (t tokenScopes) String() string {
+	switch {
+	case t.Default:
+		return "default"
+	case t.WriteAll:
+		return "write-all"
+	case t.ReadAll:
+		return "read-all"
+	case len(t.Scopes) == 0:
+		return "none"
+	}
+	names := []string{}
+	for name := range t.Scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{}
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, t.Scopes[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writes returns the scopes granted write access, in name order.
+func (t tokenScopes) writes() []string {
+	ws := []string{}
+	for name, level := range t.Scopes {
+		if level == "write" {
+			ws = append(ws, name)
+		}
+	}
+	sort.Strings(ws)
+	return ws
+}
+
+// parsePermissions reads a permissions block. A nil node means the default.
+func parsePermissions(n *yaml.Node) tokenScopes {
+	if n == nil {
+		return tokenScopes{Default: true}
+	}
+	t := tokenScopes{Scopes: map[string]string{}, Line: n.Line}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		t.WriteAll = n.Value == "write-all"
+		t.ReadAll = n.Value == "read-all"
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			t.Scopes[n.Content[i].Value] = n.Content[i+1].Value
+		}
+	}
+	return t
+}
+
+// jobScopes returns the effective GITHUB_TOKEN scope of a job.
+func jobScopes(w *Workflow, j *Job) tokenScopes {
+	if j.Permissions != nil {
+		return parsePermissions(j.Permissions)
+	}
+	return parsePermissions(w.Permissions)
+}
+
+// jobText returns what a job runs and uses, for pattern matching.
+func jobText(j *Job) string {
+	parts := []string{}
+	for _, s := range j.Steps {
+		parts = append(parts, s.Text())
+	}
+	return strings.Join(parts, "\n")
+}
+
+// unneededWrites returns the write scopes granted to a job that none of its
+// steps appear to use. Jobs that call reusable workflows are not judged.
+func unneededWrites(j *Job, t tokenScopes) []string {
+	if j.Uses != nil {
+		return nil
+	}
+	text := jobText(j)
+	unneeded := []string{}
+	for _, name := range t.writes() {
+		if re, ok := scopeUsers[name]; ok && !re.MatchString(text) {
+			unneeded = append(unneeded, name)
+		}
+	}
+	return unneeded
+}
+
+// CheckTokenPermissions reads the permissions blocks of each workflow, and
+// flags workflows that leave the GITHUB_TOKEN with the default scope, grant
+// write-all, or grant write scopes that none of their steps appear to need.
+func CheckTokenPermissions(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
+	ws, o, err := workflowsFrom(in)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	flagged := 0
+	found := []string{}
+	locs := []Location{}
+	summaries := []Result{}
+
+	for _, w := range ws {
+		problems := []string{}
+		scopes := []string{}
+		seen := map[string]bool{}
+		line := 1
+
+		for _, j := range w.Jobs {
+			t := jobScopes(w, j)
+			scopes = append(scopes, fmt.Sprintf("%s (%s)", j.ID, t))
+			seen[t.String()] = true
+
+			switch {
+			case t.Default:
+				problems = append(problems, fmt.Sprintf("job %s has no permissions block", j.ID))
+				line = j.Line
+			case t.WriteAll:
+				problems = append(problems, fmt.Sprintf("job %s has write-all", j.ID))
+				line = t.Line
+			default:
+				if unneeded := unneededWrites(j, t); len(unneeded) > 0 {
+					problems = append(problems, fmt.Sprintf("job %s does not appear to need %s: write", j.ID, strings.Join(unneeded, ", ")))
+					line = t.Line
+				}
+			}
+		}
+
+		if w.Permissions == nil && len(seen) == 1 && seen["default"] {
+			problems = []string{"no permissions block"}
+			line = 1
+		}
+
+		// Summarize once when every job has the same scope.
+		summary := strings.Join(scopes, "; ")
+		if len(seen) == 1 {
+			for s := range seen {
+				summary = s
+			}
+		}
+		if len(w.Jobs) == 0 {
+			summary = parsePermissions(w.Permissions).String()
+		}
+		summaries = append(summaries, Result{Msg: fmt.Sprintf("%s token scope: %s", w.Path, summary)})
+
+		if len(problems) > 0 {
+			flagged++
+			found = append(found, fmt.Sprintf("%s: %s", w.Path, strings.Join(problems, ", ")))
+			locs = append(locs, Location{Path: w.Path, Line: line})
+		}
+	}
+
+	res := []Result{}
+	if flagged == 0 {
+		res = append(res, Result{Msg: fmt.Sprintf("All %d workflows restrict the GITHUB_TOKEN to the scopes they use", len(ws)), Score: 0, Max: 10, Level: 1})
+	} else {
+		perc := float64(flagged) / float64(len(ws))
+		res = append(res, Result{
+			Msg:       fmt.Sprintf("%d of %d workflows have an overly broad GITHUB_TOKEN: %s", flagged, len(ws), listed(found)),
+			Score:     int(math.Ceil(10 * perc)),
+			Max:       10,
+			Level:     1,
+			Locations: locs,
+		})
+	}
+
+	return &Outcome{Results: append(res, summaries...)}, nil
+}
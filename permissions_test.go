@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePermissions(t *testing.T) {
+	tests := []struct {
+		workflow string
+		want     string
+		writes   []string
+	}{
+		{workflow: "on: push\njobs: {}", want: "default", writes: []string{}},
+		{workflow: "permissions: write-all", want: "write-all", writes: []string{}},
+		{workflow: "permissions: read-all", want: "read-all", writes: []string{}},
+		{workflow: "permissions: {}", want: "none", writes: []string{}},
+		{
+			workflow: "permissions:\n  packages: write\n  contents: read\n  id-token: write",
+			want:     "contents: read, id-token: write, packages: write",
+			writes:   []string{"id-token", "packages"},
+		},
+	}
+
+	for _, tc := range tests {
+		w, err := parseWorkflow("wf.yml", []byte(tc.workflow))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := parsePermissions(w.Permissions)
+		if got := p.String(); got != tc.want {
+			t.Errorf("parsePermissions(%q) = %q, want %q", tc.workflow, got, tc.want)
+		}
+		if got := p.writes(); !reflect.DeepEqual(got, tc.writes) {
+			t.Errorf("parsePermissions(%q).writes() = %v, want %v", tc.workflow, got, tc.writes)
+		}
+	}
+}
+
+func TestUnneededWrites(t *testing.T) {
+	w, err := parseWorkflow("wf.yml", []byte(`permissions:
+  contents: write
+  id-token: write
+  packages: write
+  actions: write
+jobs:
+  release:
+    steps:
+      - uses: sigstore/cosign-installer@v2
+      - run: git push origin v1.0.0
+  test:
+    permissions:
+      contents: read
+    steps:
+      - run: make test
+  publish:
+    permissions:
+      packages: write
+    steps:
+      - uses: docker/login-action@v2
+        with:
+          registry: ghcr.io
+  call:
+    uses: acme/workflows/.github/workflows/release.yml@main
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		// actions has no known users, so it is never reported.
+		"release": {"packages"},
+		"test":    {},
+		"publish": {},
+		// Reusable workflows are not judged.
+		"call": nil,
+	}
+	for _, j := range w.Jobs {
+		if got := unneededWrites(j, jobScopes(w, j)); !reflect.DeepEqual(got, want[j.ID]) {
+			t.Errorf("unneededWrites(%s) = %v, want %v", j.ID, got, want[j.ID])
+		}
+	}
+}
@@ -20,12 +20,13 @@ var (
 
 type Blob struct {
 	Results   []Result
+	Facts     Facts
 	Timestamp time.Time
 }
 
 type Persister interface {
-	Get(context.Context, string) ([]Result, error)
-	Set(context.Context, string, []Result) error
+	Get(context.Context, string) (*Outcome, error)
+	Set(context.Context, string, *Outcome) error
 }
 
 func NewPersist(ctx context.Context, backend string) (Persister, error) {
@@ -53,11 +54,11 @@ func NewNullPersister(_ context.Context) (*NullPersister, error) {
 	return &NullPersister{}, nil
 }
 
-func (p *NullPersister) Get(_ context.Context, _ string) ([]Result, error) {
+func (p *NullPersister) Get(_ context.Context, _ string) (*Outcome, error) {
 	return nil, nil
 }
 
-func (p *NullPersister) Set(_ context.Context, _ string, _ []Result) error {
+func (p *NullPersister) Set(_ context.Context, _ string, _ *Outcome) error {
 	return nil
 }
 
@@ -86,7 +87,7 @@ func (p *DiskPersister) keyPath(key string) string {
 	return filepath.Join(p.path, key)
 }
 
-func (p *DiskPersister) Get(ctx context.Context, key string) ([]Result, error) {
+func (p *DiskPersister) Get(ctx context.Context, key string) (*Outcome, error) {
 	kp := p.keyPath(key)
 	klog.Infof("checking %s ...", kp)
 
@@ -112,15 +113,16 @@ func (p *DiskPersister) Get(ctx context.Context, key string) ([]Result, error) {
 		return nil, fmt.Errorf("%s was too old", cutoff)
 	}
 
-	return bl.Results, nil
+	return &Outcome{Results: bl.Results, Facts: bl.Facts}, nil
 }
 
-func (p *DiskPersister) Set(ctx context.Context, key string, rs []Result) error {
+func (p *DiskPersister) Set(ctx context.Context, key string, o *Outcome) error {
 	kp := p.keyPath(key)
 	klog.Infof("setting %s ...", kp)
 	bl := &Blob{
 		Timestamp: time.Now(),
-		Results:   rs,
+		Results:   o.Results,
+		Facts:     o.Facts,
 	}
 
 	var bs bytes.Buffer
@@ -152,7 +154,7 @@ func (p *FirePersister) keyPath(key string) string {
 	return fmt.Sprintf("repos/%s", key)
 }
 
-func (p *FirePersister) Get(ctx context.Context, key string) ([]Result, error) {
+func (p *FirePersister) Get(ctx context.Context, key string) (*Outcome, error) {
 	kp := p.keyPath(key)
 	//	klog.Infof("checking %s ...", kp)
 
@@ -189,15 +191,16 @@ func (p *FirePersister) Get(ctx context.Context, key string) ([]Result, error) {
 		return nil, fmt.Errorf("%s was too old", cutoff)
 	}
 
-	return bl.Results, nil
+	return &Outcome{Results: bl.Results, Facts: bl.Facts}, nil
 }
 
-func (p *FirePersister) Set(ctx context.Context, key string, rs []Result) error {
+func (p *FirePersister) Set(ctx context.Context, key string, o *Outcome) error {
 	kp := p.keyPath(key)
 	klog.Infof("setting %s ...", kp)
 	bl := &Blob{
 		Timestamp: time.Now(),
-		Results:   rs,
+		Results:   o.Results,
+		Facts:     o.Facts,
 	}
 
 	var bs bytes.Buffer
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var shaRE = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// unpinned returns why an action or reusable workflow reference is not
+// immutable, or "" if it is pinned or does not need to be. References to the
+// repository itself, and to actions owned by the same owner, are trusted.
+func unpinned(uses string, owner string) string {
+	switch {
+	case strings.HasPrefix(uses, "./"):
+		return ""
+	case strings.HasPrefix(uses, "docker://"):
+		if strings.Contains(uses, "@sha256:") {
+			return ""
+		}
+		return "image is not pinned to a digest"
+	}
+
+	action, ref, ok := strings.Cut(uses, "@")
+	if !ok {
+		return "no ref"
+	}
+	if owner != "" && strings.EqualFold(strings.Split(action, "/")[0], owner) {
+		return ""
+	}
+	if shaRE.MatchString(ref) {
+		return ""
+	}
+	return fmt.Sprintf("%s is not a commit SHA", ref)
+}
+
+// CheckActionPinning finds workflow steps and jobs that use third-party
+// actions, reusable workflows or images by mutable reference.
+func CheckActionPinning(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
+	ws, o, err := workflowsFrom(in)
+	if o != nil || err != nil {
+		return o, err
+	}
+
+	total := 0
+	found := []string{}
+	locs := []Location{}
+	check := func(w *Workflow, j *Job, s *Step, uses *yaml.Node) {
+		if uses == nil || uses.Value == "" {
+			return
+		}
+		total++
+		if why := unpinned(uses.Value, c.Owner); why != "" {
+			// Unnamed steps are labelled by what they use, which the message already says.
+			where := w.where(j, nil)
+			if s != nil && s.Name != "" {
+				where = w.where(j, s)
+			}
+			found = append(found, fmt.Sprintf("%s uses %s (%s)", where, uses.Value, why))
+			locs = append(locs, Location{Path: w.Path, Line: uses.Line})
+		}
+	}
+
+	for _, w := range ws {
+		for _, j := range w.Jobs {
+			check(w, j, nil, j.Uses)
+			for _, s := range j.Steps {
+				check(w, j, s, s.Uses)
+			}
+		}
+	}
+
+	if total == 0 {
+		return &Outcome{Results: []Result{{Msg: "Workflows do not use any actions"}}}, nil
+	}
+	if len(found) == 0 {
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("All %d action references are pinned to a commit SHA or digest", total), Score: 0, Max: 10, Level: 1}}}, nil
+	}
+
+	perc := float64(len(found)) / float64(total)
+	return &Outcome{Results: []Result{{
+		Msg:       fmt.Sprintf("%d of %d action references are not pinned: %s", len(found), total, listed(found)),
+		Score:     int(math.Ceil(10 * perc)),
+		Max:       10,
+		Level:     1,
+		Locations: locs,
+	}}}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUnpinned(t *testing.T) {
+	tests := []struct {
+		uses  string
+		owner string
+		want  string
+	}{
+		{uses: "actions/checkout@v3", want: "v3 is not a commit SHA"},
+		{uses: "actions/checkout@main", want: "main is not a commit SHA"},
+		{uses: "actions/checkout@8f4b7f84864484a7bf31766abe9204da3cbe65b3"},
+		// Abbreviated SHAs can collide with tags.
+		{uses: "actions/checkout@8f4b7f8", want: "8f4b7f8 is not a commit SHA"},
+		{uses: "acme/workflows/.github/workflows/release.yml@v1", want: "v1 is not a commit SHA"},
+		{uses: "actions/checkout", want: "no ref"},
+		{uses: "./.github/actions/setup"},
+		{uses: "docker://alpine:3", want: "image is not pinned to a digest"},
+		{uses: "docker://alpine@sha256:4edbd2beb5f78b1014028f4fbb99f3237d9561100b6881aabbf5acce2c4f9454"},
+		{uses: "acme/setup@v1", owner: "acme"},
+		{uses: "Acme/setup@v1", owner: "acme"},
+		{uses: "acme-tools/setup@v1", owner: "acme", want: "v1 is not a commit SHA"},
+	}
+
+	for _, tc := range tests {
+		if got := unpinned(tc.uses, tc.owner); got != tc.want {
+			t.Errorf("unpinned(%q, %q) = %q, want %q", tc.uses, tc.owner, got, tc.want)
+		}
+	}
+}
+
+func TestCheckActionPinning(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/ci.yml", []byte(`on: push
+jobs:
+  build:
+    steps:
+      - uses: actions/checkout@8f4b7f84864484a7bf31766abe9204da3cbe65b3
+      - name: Set up Go
+        uses: actions/setup-go@v3
+      - run: make
+  release:
+    uses: acme/workflows/.github/workflows/release.yml@main
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := CheckActionPinning(context.Background(), &Config{}, Facts{Workflows: []*Workflow{w}, WorkflowsLoaded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(o.Results))
+	}
+
+	r := o.Results[0]
+	if r.Score != 7 || r.Max != 10 {
+		t.Errorf("score = %d/%d, want 7/10", r.Score, r.Max)
+	}
+	for _, want := range []string{`job build step "Set up Go" uses actions/setup-go@v3`, "job release uses acme/workflows"} {
+		if !strings.Contains(r.Msg, want) {
+			t.Errorf("message %q does not contain %q", r.Msg, want)
+		}
+	}
+	if len(r.Locations) != 2 || r.Locations[0].Line != 7 || r.Locations[1].Line != 10 {
+		t.Errorf("locations = %+v, want lines 7 and 10", r.Locations)
+	}
+}
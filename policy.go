@@ -0,0 +1,78 @@
+package main
+
+import "fmt"
+
+// Exit codes used by the CLI.
+const (
+	// exitPass means every check ran and the policy was satisfied.
+	exitPass = 0
+	// exitPolicy means the scan succeeded but the policy was violated.
+	exitPolicy = 1
+	// exitFailed means the scan could not be completed.
+	exitFailed = 2
+)
+
+// Policy is a set of requirements that a report must satisfy.
+type Policy struct {
+	// MinLevel is the lowest acceptable YOLO compliance level (0 to -4).
+	MinLevel int
+	// MaxPercent is the highest acceptable YOLO score percentage.
+	MaxPercent int
+	// Require lists checks that must run without error and score zero YOLO points.
+	Require []string
+}
+
+// Violations returns a description of each way in which rep violates the policy.
+func (p *Policy) Violations(rep *Report) []string {
+	vs := []string{}
+
+	if -rep.Level < p.MinLevel {
+		vs = append(vs, fmt.Sprintf("YOLO compliance level %d is below the minimum of %d", -rep.Level, p.MinLevel))
+	}
+
+	if rep.Percent > p.MaxPercent {
+		vs = append(vs, fmt.Sprintf("YOLO score of %d%% is above the maximum of %d%%", rep.Percent, p.MaxPercent))
+	}
+
+	for _, id := range p.Require {
+		var found *CheckReport
+		for i := range rep.Checks {
+			if rep.Checks[i].ID == id {
+				found = &rep.Checks[i]
+				break
+			}
+		}
+
+		switch {
+		case found == nil:
+			vs = append(vs, fmt.Sprintf("required check %s did not run", id))
+		case found.Error != "":
+			vs = append(vs, fmt.Sprintf("required check %s failed: %s", id, found.Error))
+		case found.Skipped != "":
+			vs = append(vs, fmt.Sprintf("required check %s was skipped: %s", id, found.Skipped))
+		default:
+			score := 0
+			for _, r := range found.Results {
+				score += r.Score
+			}
+			if score > 0 {
+				vs = append(vs, fmt.Sprintf("required check %s scored %d YOLO points", id, score))
+			}
+		}
+	}
+
+	return vs
+}
+
+// exitCode returns the CLI exit code for a finished scan.
+func exitCode(rep *Report, violations []string) int {
+	for _, c := range rep.Checks {
+		if c.Error != "" {
+			return exitFailed
+		}
+	}
+	if len(violations) > 0 {
+		return exitPolicy
+	}
+	return exitPass
+}
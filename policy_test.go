@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []CheckReport
+		violations []string
+		want       int
+	}{
+		{name: "pass", checks: []CheckReport{{ID: "commits"}}, want: exitPass},
+		{name: "violation", checks: []CheckReport{{ID: "commits"}}, violations: []string{"too YOLO"}, want: exitPolicy},
+		{name: "check error", checks: []CheckReport{{ID: "commits", Error: "boom"}}, want: exitFailed},
+		{name: "check error outranks a violation", checks: []CheckReport{{ID: "commits", Error: "boom"}}, violations: []string{"too YOLO"}, want: exitFailed},
+		{name: "skipped checks are not errors", checks: []CheckReport{{ID: "commits", Skipped: "needs network access"}}, want: exitPass},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exitCode(&Report{Checks: tc.checks}, tc.violations); got != tc.want {
+				t.Errorf("exitCode() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestViolations(t *testing.T) {
+	rep := &Report{
+		Level:   2,
+		Percent: 40,
+		Checks: []CheckReport{
+			{ID: "commits", Results: []Result{{Score: 3, Max: 10}}},
+			{ID: "private-keys", Results: []Result{{Score: 0, Max: 10}}},
+			{ID: "releaser", Error: "boom"},
+			{ID: "signed-image", Skipped: "no image"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []string
+	}{
+		{
+			name:   "lenient",
+			policy: Policy{MinLevel: -4, MaxPercent: 100, Require: []string{"private-keys"}},
+			want:   []string{},
+		},
+		{
+			name:   "level and percentage",
+			policy: Policy{MinLevel: -1, MaxPercent: 25},
+			want: []string{
+				"YOLO compliance level -2 is below the minimum of -1",
+				"YOLO score of 40% is above the maximum of 25%",
+			},
+		},
+		{
+			name:   "required checks",
+			policy: Policy{MinLevel: -4, MaxPercent: 100, Require: []string{"commits", "releaser", "signed-image", "sbom"}},
+			want: []string{
+				"required check commits scored 3 YOLO points",
+				"required check releaser failed: boom",
+				"required check signed-image was skipped: no image",
+				"required check sbom did not run",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.Violations(rep); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Violations() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
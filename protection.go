@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Protection is what a forge enforces for pushes to a branch.
+type Protection struct {
+	// RequiredReviews is the number of approving reviews required to merge, if any.
+	RequiredReviews      int
+	RequiresSignatures   bool
+	RequiresStatusChecks bool
+	AllowsForcePushes    bool
+	AllowsDeletions      bool
+	// AdminsCanBypass is set if administrators are exempt from the protections.
+	AdminsCanBypass bool
+	// Sources name the rules that the protections come from.
+	Sources []string
+	// Readable is set if the token can read every rule, so that an empty
+	// Sources means the branch is unprotected.
+	Readable bool
+}
+
+// ProtectionForge is implemented by forges that can report branch protection.
+type ProtectionForge interface {
+	Protection(ctx context.Context, owner, name, branch string) (*Protection, error)
+}
+
+// CheckBranchProtection scores the protections that are missing from the default branch.
+func CheckBranchProtection(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	pf, ok := c.Forge.(ProtectionForge)
+	if !ok {
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("Branch protection is not supported on %s", c.Forge.Host())}}}, nil
+	}
+
+	p, err := pf.Protection(ctx, c.Owner, c.Name, c.DefaultBranch)
+	if err != nil {
+		return nil, fmt.Errorf("protection: %w", err)
+	}
+
+	// Reading protection rules may require admin access, so their absence is
+	// only conclusive, and scored, when the token can read them.
+	if len(p.Sources) == 0 && !p.Readable {
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("No branch protection rule or ruleset that the token can read applies to %s", c.DefaultBranch)}}}, nil
+	}
+
+	score := 0
+	off := []string{}
+	if p.RequiredReviews == 0 {
+		score += 3
+		off = append(off, "required reviews")
+	}
+	if !p.RequiresSignatures {
+		score += 2
+		off = append(off, "required signatures")
+	}
+	if !p.RequiresStatusChecks {
+		score += 2
+		off = append(off, "required status checks")
+	}
+	if p.AllowsForcePushes {
+		score++
+		off = append(off, "force pushes are allowed")
+	}
+	if p.AllowsDeletions {
+		score++
+		off = append(off, "deletion is allowed")
+	}
+	if p.AdminsCanBypass {
+		score++
+		off = append(off, "admins can bypass")
+	}
+
+	msg := fmt.Sprintf("%s is protected by %s", c.DefaultBranch, strings.Join(p.Sources, ", "))
+	if len(p.Sources) == 0 {
+		msg = fmt.Sprintf("%s is not protected by any branch protection rule or ruleset", c.DefaultBranch)
+	}
+	if len(off) > 0 {
+		msg = fmt.Sprintf("%s; missing: %s", msg, strings.Join(off, ", "))
+	}
+	return &Outcome{Results: []Result{{Msg: msg, Score: score, Max: 10, Level: 1}}}, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// protectionForge is a GitHub forge whose branch protection is fixed.
+type protectionForge struct {
+	*GitHub
+	p *Protection
+}
+
+func (f *protectionForge) Protection(context.Context, string, string, string) (*Protection, error) {
+	return f.p, nil
+}
+
+func TestRulesetApplies(t *testing.T) {
+	tests := []struct {
+		include, exclude []githubv4.String
+		branch           string
+		defaultBranch    bool
+		want             bool
+	}{
+		{include: []githubv4.String{"~ALL"}, branch: "main", want: true},
+		{include: []githubv4.String{"~DEFAULT_BRANCH"}, branch: "main", defaultBranch: true, want: true},
+		{include: []githubv4.String{"~DEFAULT_BRANCH"}, branch: "dev"},
+		{include: []githubv4.String{"refs/heads/main"}, branch: "main", want: true},
+		{include: []githubv4.String{"refs/heads/release-*"}, branch: "release-1", want: true},
+		{include: []githubv4.String{"refs/heads/release-*"}, branch: "main"},
+		{include: []githubv4.String{"~ALL"}, exclude: []githubv4.String{"refs/heads/main"}, branch: "main"},
+		{branch: "main"},
+	}
+	for _, tc := range tests {
+		if got := rulesetApplies(tc.include, tc.exclude, tc.branch, tc.defaultBranch); got != tc.want {
+			t.Errorf("rulesetApplies(%v, %v, %q, %v) = %v, want %v", tc.include, tc.exclude, tc.branch, tc.defaultBranch, got, tc.want)
+		}
+	}
+}
+
+func TestCheckBranchProtection(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         Protection
+		wantScore int
+		wantMax   int
+		wantMsg   string
+	}{
+		{
+			name:    "unreadable",
+			p:       Protection{AllowsForcePushes: true, AllowsDeletions: true, AdminsCanBypass: true},
+			wantMsg: "No branch protection rule or ruleset that the token can read applies to main",
+		},
+		{
+			name:      "unprotected",
+			p:         Protection{AllowsForcePushes: true, AllowsDeletions: true, AdminsCanBypass: true, Readable: true},
+			wantScore: 10,
+			wantMax:   10,
+			wantMsg:   "main is not protected by any branch protection rule or ruleset; missing: required reviews, required signatures, required status checks, force pushes are allowed, deletion is allowed, admins can bypass",
+		},
+		{
+			name:      "fully protected",
+			p:         Protection{RequiredReviews: 1, RequiresSignatures: true, RequiresStatusChecks: true, Sources: []string{`ruleset "main"`}},
+			wantScore: 0,
+			wantMax:   10,
+			wantMsg:   `main is protected by ruleset "main"`,
+		},
+		{
+			name:      "everything missing",
+			p:         Protection{AllowsForcePushes: true, AllowsDeletions: true, AdminsCanBypass: true, Sources: []string{`branch protection rule "*"`}},
+			wantScore: 10,
+			wantMax:   10,
+			wantMsg:   `main is protected by branch protection rule "*"; missing: required reviews, required signatures, required status checks, force pushes are allowed, deletion is allowed, admins can bypass`,
+		},
+		{
+			name:      "admins can bypass",
+			p:         Protection{RequiredReviews: 2, RequiresSignatures: true, RequiresStatusChecks: true, AdminsCanBypass: true, Sources: []string{`branch protection rule "main"`, `ruleset "main"`}},
+			wantScore: 1,
+			wantMax:   10,
+			wantMsg:   `main is protected by branch protection rule "main", ruleset "main"; missing: admins can bypass`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.p
+			f := &protectionForge{GitHub: &GitHub{base: defaultGitHubURL}, p: &p}
+			o, err := CheckBranchProtection(context.Background(), &Config{Forge: f, Owner: "owner", Name: "repo", DefaultBranch: "main"}, Facts{})
+			if err != nil {
+				t.Fatalf("CheckBranchProtection: %v", err)
+			}
+			if len(o.Results) != 1 {
+				t.Fatalf("got %d results, want 1", len(o.Results))
+			}
+			if r := o.Results[0]; r.Score != tc.wantScore || r.Max != tc.wantMax || r.Msg != tc.wantMsg {
+				t.Errorf("CheckBranchProtection() = %q %d/%d, want %q %d/%d", r.Msg, r.Score, r.Max, tc.wantMsg, tc.wantScore, tc.wantMax)
+			}
+		})
+	}
+}
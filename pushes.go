@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxListed is the number of findings named in a result message. Every
+// finding is still included in the result's locations.
+const maxListed = 10
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// listed joins the first maxListed items, noting how many were left out.
+func listed(items []string) string {
+	if len(items) <= maxListed {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:maxListed], ", "), len(items)-maxListed)
+}
+
+// selfMerged reports whether a pull request was merged by its author without
+// a review from anyone else.
+func selfMerged(pr PullRequest) bool {
+	if pr.MergedBy.Login == "" || pr.MergedBy.Login != pr.Author.Login {
+		return false
+	}
+	for _, r := range pr.Reviews {
+		if r.Author != nil && r.Author.Login != pr.Author.Login {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckDirectPushes finds commits on the default branch that did not go
+// through a pull request, and pull requests that were merged by their own
+// author without anyone else reviewing them.
+func CheckDirectPushes(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	cs, err := c.Forge.Commits(ctx, c.Owner, c.Name, c.DefaultBranch, c.History)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commits: %w", err)
+	}
+	if len(cs) == 0 {
+		return &Outcome{Results: []Result{{Msg: fmt.Sprintf("No commits found for %s", c.DefaultBranch)}}}, nil
+	}
+
+	pushed := []string{}
+	pushedLocs := []Location{}
+	prs := map[int]bool{}
+	merged := []string{}
+	mergedLocs := []Location{}
+
+	for _, co := range cs {
+		pr := co.AssociatedMergeRequest
+		if pr.Number == 0 {
+			u := c.Forge.CommitURL(c.Owner, c.Name, co.SHA)
+			pushed = append(pushed, fmt.Sprintf("%s (%s)", shortSHA(co.SHA), u))
+			pushedLocs = append(pushedLocs, Location{URL: u})
+			continue
+		}
+
+		if prs[pr.Number] {
+			continue
+		}
+		prs[pr.Number] = true
+		if selfMerged(pr) {
+			u := c.Forge.PullRequestURL(c.Owner, c.Name, pr.Number)
+			merged = append(merged, fmt.Sprintf("#%d (%s)", pr.Number, u))
+			mergedLocs = append(mergedLocs, Location{URL: u})
+		}
+	}
+
+	res := []Result{}
+	percPushed := float64(len(pushed)) / float64(len(cs))
+	if len(pushed) == 0 {
+		res = append(res, Result{Msg: fmt.Sprintf("All of the last %d commits on %s went through a pull request", len(cs), c.DefaultBranch), Score: 0, Max: 10, Level: 1})
+	} else {
+		res = append(res, Result{
+			Msg:       fmt.Sprintf("%d of the last %d commits were pushed to %s without a pull request: %s", len(pushed), len(cs), c.DefaultBranch, listed(pushed)),
+			Score:     int(math.Ceil(10 * percPushed)),
+			Max:       10,
+			Level:     1,
+			Locations: pushedLocs,
+		})
+	}
+
+	if len(prs) == 0 {
+		return &Outcome{Results: res}, nil
+	}
+
+	percMerged := float64(len(merged)) / float64(len(prs))
+	if len(merged) == 0 {
+		res = append(res, Result{Msg: fmt.Sprintf("None of the last %d pull requests were merged by their author without another reviewer", len(prs)), Score: 0, Max: 5, Level: 1})
+	} else {
+		res = append(res, Result{
+			Msg:       fmt.Sprintf("%d of the last %d pull requests were merged by their author without another reviewer: %s", len(merged), len(prs), listed(merged)),
+			Score:     int(math.Ceil(5 * percMerged)),
+			Max:       5,
+			Level:     1,
+			Locations: mergedLocs,
+		})
+	}
+
+	return &Outcome{Results: res}, nil
+}
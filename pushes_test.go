@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// historyForge is a GitHub forge whose commit history is fixed.
+type historyForge struct {
+	*GitHub
+	commits []Commit
+}
+
+func (f *historyForge) Commits(context.Context, string, string, string, HistoryOptions) ([]Commit, error) {
+	return f.commits, nil
+}
+
+func TestSelfMerged(t *testing.T) {
+	alice := User{Login: "alice"}
+	bob := User{Login: "bob"}
+	tests := []struct {
+		name string
+		pr   PullRequest
+		want bool
+	}{
+		{name: "merged by someone else", pr: PullRequest{Author: alice, MergedBy: bob}},
+		{name: "merged by author", pr: PullRequest{Author: alice, MergedBy: alice}, want: true},
+		{name: "merged by author after their own review", pr: PullRequest{Author: alice, MergedBy: alice, Reviews: []Review{{Author: &alice, State: "COMMENTED"}}}, want: true},
+		{name: "merged by author after another review", pr: PullRequest{Author: alice, MergedBy: alice, Reviews: []Review{{Author: &bob, State: "APPROVED"}}}},
+		{name: "review by a deleted user", pr: PullRequest{Author: alice, MergedBy: alice, Reviews: []Review{{State: "APPROVED"}}}, want: true},
+		{name: "merger unknown", pr: PullRequest{Author: alice}},
+	}
+	for _, tc := range tests {
+		if got := selfMerged(tc.pr); got != tc.want {
+			t.Errorf("%s: selfMerged() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckDirectPushes(t *testing.T) {
+	alice := User{Login: "alice"}
+	bob := User{Login: "bob"}
+	f := &historyForge{GitHub: &GitHub{base: defaultGitHubURL}, commits: []Commit{
+		{SHA: "1111111111", AssociatedMergeRequest: PullRequest{Number: 1, Author: alice, MergedBy: bob}},
+		{SHA: "2222222222"},
+		// A pull request with several commits is counted once.
+		{SHA: "3333333333", AssociatedMergeRequest: PullRequest{Number: 2, Author: alice, MergedBy: alice}},
+		{SHA: "4444444444", AssociatedMergeRequest: PullRequest{Number: 2, Author: alice, MergedBy: alice}},
+	}}
+
+	o, err := CheckDirectPushes(context.Background(), &Config{Forge: f, Owner: "owner", Name: "repo", DefaultBranch: "main"}, Facts{})
+	if err != nil {
+		t.Fatalf("CheckDirectPushes: %v", err)
+	}
+
+	want := []Result{
+		{
+			Msg:       "1 of the last 4 commits were pushed to main without a pull request: 2222222 (https://github.com/owner/repo/commit/2222222222)",
+			Score:     3,
+			Max:       10,
+			Level:     1,
+			Locations: []Location{{URL: "https://github.com/owner/repo/commit/2222222222"}},
+		},
+		{
+			Msg:       "1 of the last 2 pull requests were merged by their author without another reviewer: #2 (https://github.com/owner/repo/pull/2)",
+			Score:     3,
+			Max:       5,
+			Level:     1,
+			Locations: []Location{{URL: "https://github.com/owner/repo/pull/2"}},
+		},
+	}
+	if !reflect.DeepEqual(o.Results, want) {
+		t.Errorf("CheckDirectPushes() = %+v, want %+v", o.Results, want)
+	}
+}
+
+func TestListed(t *testing.T) {
+	items := []string{}
+	for i := 0; i < maxListed+2; i++ {
+		items = append(items, "x")
+	}
+	if got, want := listed(items[:2]), "x, x"; got != want {
+		t.Errorf("listed() = %q, want %q", got, want)
+	}
+	if got, want := listed(items), "x, x, x, x, x, x, x, x, x, x and 2 more"; got != want {
+		t.Errorf("listed() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"k8s.io/klog/v2"
+)
+
+// maxSecondaryRetries is how many times a request is retried after hitting a secondary rate limit.
+const maxSecondaryRetries = 3
+
+// maxSecondaryWait is the longest a request waits in total for secondary rate
+// limits, so that one request cannot hold up a batch or a web request for long.
+const maxSecondaryWait = 3 * time.Minute
+
+// RateLimit is the most recently observed budget for a GitHub API rate limit.
+type RateLimit struct {
+	// Resource is the rate limit bucket, such as "core" or "graphql".
+	Resource  string    `json:"resource"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	// Cost is the number of points the last GraphQL query consumed, if known.
+	Cost     int       `json:"cost,omitempty"`
+	Observed time.Time `json:"observed"`
+}
+
+// RateLimitError is returned instead of making a request when a rate limit is exhausted.
+type RateLimitError struct {
+	Resource string
+	Reset    time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited until %s", e.Reset.Local().Format("15:04"))
+}
+
+// graphqlRateLimit is requested alongside each GraphQL query, so that the
+// budget and the cost of the query are known once it returns.
+type graphqlRateLimit struct {
+	Cost      githubv4.Int
+	Limit     githubv4.Int
+	Remaining githubv4.Int
+	ResetAt   githubv4.DateTime
+}
+
+// RateLimits tracks the budget of each rate limit resource for a single set of credentials.
+type RateLimits struct {
+	mu     sync.Mutex
+	limits map[string]RateLimit
+}
+
+func (rl *RateLimits) observe(l RateLimit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.limits == nil {
+		rl.limits = map[string]RateLimit{}
+	}
+	// Response headers do not carry the query cost, so keep the last one seen.
+	if l.Cost == 0 {
+		l.Cost = rl.limits[l.Resource].Cost
+	}
+	l.Observed = time.Now()
+	rl.limits[l.Resource] = l
+}
+
+// observeGraphQL records the budget reported in the response to a GraphQL query.
+func (rl *RateLimits) observeGraphQL(l graphqlRateLimit) {
+	// GitHub Enterprise Server does not report a budget when rate limiting is disabled.
+	if l.Limit == 0 {
+		return
+	}
+	rl.observe(RateLimit{
+		Resource:  "graphql",
+		Limit:     int(l.Limit),
+		Remaining: int(l.Remaining),
+		Reset:     l.ResetAt.Time,
+		Cost:      int(l.Cost),
+	})
+}
+
+// exhausted returns an error if resource has no budget left until it resets.
+func (rl *RateLimits) exhausted(resource string) *RateLimitError {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	l, ok := rl.limits[resource]
+	if !ok || l.Remaining > 0 || time.Now().After(l.Reset) {
+		return nil
+	}
+	return &RateLimitError{Resource: resource, Reset: l.Reset}
+}
+
+// Snapshot returns the current budgets, sorted by resource.
+func (rl *RateLimits) Snapshot() []RateLimit {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	ls := []RateLimit{}
+	for _, l := range rl.limits {
+		ls = append(ls, l)
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Resource < ls[j].Resource })
+	return ls
+}
+
+// parseRateLimit reads the X-RateLimit-* headers of a GitHub API response.
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	resource := h.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	return RateLimit{Resource: resource, Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// resourceFor guesses the rate limit resource a request is billed to.
+func resourceFor(req *http.Request) string {
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		return "graphql"
+	}
+	return "core"
+}
+
+// rateLimitTransport records the rate limit budget reported by GitHub, fails
+// fast once it is exhausted, and backs off when a secondary rate limit is hit.
+type rateLimitTransport struct {
+	base   http.RoundTripper
+	limits *RateLimits
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limits.exhausted(resourceFor(req)); err != nil {
+		return nil, err
+	}
+
+	waited := time.Duration(0)
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewind body: %w", err)
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+
+		l, ok := parseRateLimit(resp.Header)
+		if ok {
+			t.limits.observe(l)
+		}
+
+		if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+
+		// The primary rate limit is exhausted: there is nothing to do but wait for the reset.
+		if ok && l.Remaining == 0 {
+			resp.Body.Close()
+			return nil, &RateLimitError{Resource: l.Resource, Reset: l.Reset}
+		}
+
+		wait, secondary, err := secondaryLimit(resp, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if !secondary {
+			return resp, nil
+		}
+		if attempt >= maxSecondaryRetries || waited+wait > maxSecondaryWait || (req.Body != nil && req.GetBody == nil) {
+			resp.Body.Close()
+			return nil, &RateLimitError{Resource: resourceFor(req), Reset: time.Now().Add(wait)}
+		}
+		resp.Body.Close()
+
+		klog.Warningf("secondary rate limit for %s, retrying in %s", req.URL, wait)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		waited += wait
+	}
+}
+
+// secondaryLimit determines whether resp was rejected by a secondary rate
+// limit, and if so, how long to wait before retrying. The body of resp is
+// preserved for the caller.
+func secondaryLimit(resp *http.Response, attempt int) (time.Duration, bool, error) {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true, nil
+		}
+	}
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return 0, false, fmt.Errorf("read body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(bs))
+
+	body := strings.ToLower(string(bs))
+	if !strings.Contains(body, "secondary rate limit") && !strings.Contains(body, "abuse detection") {
+		return 0, false, nil
+	}
+	// Without a Retry-After header, GitHub asks for at least a minute between retries.
+	return time.Minute << attempt, true, nil
+}
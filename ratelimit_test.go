@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRateLimitTransportWaitCap(t *testing.T) {
+	calls := 0
+	rt := &rateLimitTransport{
+		base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			h := http.Header{}
+			h.Set("Retry-After", "600")
+			return &http.Response{StatusCode: http.StatusForbidden, Header: h, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}),
+		limits: &RateLimits{},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/o/r", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = rt.RoundTrip(req)
+
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("RoundTrip() error = %v, want a RateLimitError", err)
+	}
+	if calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("made %d requests in %s, want 1 request without waiting longer than %s", calls, time.Since(start), maxSecondaryWait)
+	}
+}
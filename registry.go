@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Input is something a check requires in order to run.
+type Input int
+
+const (
+	// NeedsRepo checks talk to the forge hosting the repository.
+	NeedsRepo Input = 1 << iota
+	// NeedsImage checks inspect a container image.
+	NeedsImage
+	// NeedsClone checks read files from a checkout of the repository.
+	NeedsClone
+)
+
+// networked reports whether the input can only be satisfied over the network.
+func (i Input) networked() bool {
+	return i&(NeedsRepo|NeedsImage) != 0
+}
+
+func (i Input) String() string {
+	names := []string{}
+	if i&NeedsRepo != 0 {
+		names = append(names, "repo")
+	}
+	if i&NeedsImage != 0 {
+		names = append(names, "image")
+	}
+	if i&NeedsClone != 0 {
+		names = append(names, "clone")
+	}
+	return strings.Join(names, ",")
+}
+
+// CheckDef describes a registered check.
+type CheckDef struct {
+	// ID is stable: it is used on the command-line and as part of the persistence key.
+	ID          string
+	Title       string
+	Description string
+	Level       int
+	// Weight multiplies the score of every result produced by this check.
+	Weight int
+	Inputs Input
+	// Deps are the IDs of checks that must run before this one.
+	Deps []string
+	// OptIn checks only run when they are named by --checks.
+	OptIn bool
+	// History checks read the commit history, so the history options are part
+	// of their persistence key.
+	History bool
+	Run     Checker
+}
+
+var registry = map[string]*CheckDef{}
+
+// order is the order in which checks were registered, used for display.
+var order = []string{}
+
+func register(d *CheckDef) {
+	if _, ok := registry[d.ID]; ok {
+		panic(fmt.Sprintf("check %q registered twice", d.ID))
+	}
+	if d.Weight == 0 {
+		d.Weight = 1
+	}
+	registry[d.ID] = d
+	order = append(order, d.ID)
+}
+
+func init() {
+	// Release assets are fetched from the forge when it is available, but the
+	// rest of the sbom check works on a local checkout alone.
+	register(&CheckDef{
+		ID:          "sbom",
+		Title:       "SBOM",
+		Description: "Looks for SPDX and CycloneDX documents in release assets and the repository, and for workflow steps that generate them; release assets are not examined with --path",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Run:         CheckSBOM,
+	})
+	register(&CheckDef{
+		ID:          "sbom-quality",
+		Title:       "SBOM quality",
+		Description: "Grades the SBOMs found for the repository and image against the NTIA minimum elements",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Deps:        []string{"sbom"},
+		Run:         CheckSBOMQuality,
+	})
+	register(&CheckDef{
+		ID:          "releaser",
+		Title:       "Automated releases",
+		Description: "Checks what fraction of recent releases were cut by bots or apps rather than humans",
+		Level:       0,
+		Inputs:      NeedsRepo,
+		Run:         CheckReleaserV2,
+	})
+	register(&CheckDef{
+		ID:          "commits",
+		Title:       "Commit hygiene",
+		Description: "Measures signing, review, approval and PR usage for recent commits",
+		Level:       1,
+		Inputs:      NeedsRepo,
+		History:     true,
+		Run:         CheckCommits,
+	})
+	register(&CheckDef{
+		ID:          "commit-authors",
+		Title:       "Commit hygiene by author",
+		Description: "Breaks commit signing, review, approval and PR usage down by author, separating bots from humans",
+		Level:       1,
+		Inputs:      NeedsRepo,
+		OptIn:       true,
+		History:     true,
+		Run:         CheckCommitAuthors,
+	})
+	register(&CheckDef{
+		ID:          "direct-pushes",
+		Title:       "Direct pushes",
+		Description: "Lists commits pushed to the default branch without a PR, and PRs merged by their author without another reviewer",
+		Level:       1,
+		Inputs:      NeedsRepo,
+		History:     true,
+		Run:         CheckDirectPushes,
+	})
+	register(&CheckDef{
+		ID:          "branch-protection",
+		Title:       "Branch protection",
+		Description: "Checks the branch protection rules and rulesets that apply to the default branch",
+		Level:       1,
+		Inputs:      NeedsRepo,
+		Run:         CheckBranchProtection,
+	})
+	register(&CheckDef{
+		ID:          "private-keys",
+		Title:       "Private keys",
+		Description: "Scans a clone of the repository for checked-in private keys",
+		Level:       2,
+		Inputs:      NeedsClone,
+		Run:         CheckPrivateKeys,
+	})
+	register(&CheckDef{
+		ID:          "workflows",
+		Title:       "GitHub Actions workflows",
+		Description: "Parses the GitHub Actions workflows in a clone of the repository for the workflow checks",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Run:         CheckWorkflows,
+	})
+	register(&CheckDef{
+		ID:          "action-pinning",
+		Title:       "Action pinning",
+		Description: "Flags workflow steps that use third-party actions or images by tag or branch instead of a commit SHA or digest",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Deps:        []string{"workflows"},
+		Run:         CheckActionPinning,
+	})
+	register(&CheckDef{
+		ID:          "script-injection",
+		Title:       "Script injection",
+		Description: "Flags workflows that interpolate attacker-controlled event data into run steps, or run pull request code with a privileged trigger",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Deps:        []string{"workflows"},
+		Run:         CheckScriptInjection,
+	})
+	register(&CheckDef{
+		ID:          "token-permissions",
+		Title:       "Token permissions",
+		Description: "Summarizes the GITHUB_TOKEN scope of each workflow, flagging default, write-all and apparently unneeded write permissions",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Deps:        []string{"workflows"},
+		Run:         CheckTokenPermissions,
+	})
+	register(&CheckDef{
+		ID:          "ci-credentials",
+		Title:       "CI credentials",
+		Description: "Checks whether workflows deploy and publish with long-lived stored secrets or short-lived OIDC credentials",
+		Level:       1,
+		Inputs:      NeedsClone,
+		Deps:        []string{"workflows"},
+		Run:         CheckCICredentials,
+	})
+	register(&CheckDef{
+		ID:          "signed-image",
+		Title:       "Signed images",
+		Description: "Verifies cosign signatures for the given or discovered container images",
+		Level:       1,
+		Inputs:      NeedsImage,
+		Deps:        []string{"private-keys"},
+		Run:         CheckSignedImage,
+	})
+
+	if err := validateDeps(); err != nil {
+		panic(err)
+	}
+}
+
+// validateDeps returns an error if a check depends on an unknown check, or
+// if the dependencies form a cycle, which would leave schedule waiting forever.
+func validateDeps() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("check dependency cycle: %s", strings.Join(append(path, id), " -> "))
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, dep := range registry[id].Deps {
+			if _, ok := registry[dep]; !ok {
+				return fmt.Errorf("check %q depends on unknown check %q", id, dep)
+			}
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+
+	for _, id := range order {
+		if err := visit(id, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectChecks returns the checks to run, in registration order. If enabled is
+// non-empty only those checks and their dependencies are returned, otherwise
+// every check that is not opt-in. Checks listed in skipped are never returned.
+func selectChecks(enabled []string, skipped []string) ([]*CheckDef, error) {
+	want := map[string]bool{}
+	if len(enabled) == 0 {
+		for _, id := range order {
+			want[id] = !registry[id].OptIn
+		}
+	}
+
+	var add func(id string) error
+	add = func(id string) error {
+		d, ok := registry[id]
+		if !ok {
+			return fmt.Errorf("unknown check: %q", id)
+		}
+		if want[id] {
+			return nil
+		}
+		want[id] = true
+		for _, dep := range d.Deps {
+			if err := add(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, id := range enabled {
+		if err := add(id); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range skipped {
+		if _, ok := registry[id]; !ok {
+			return nil, fmt.Errorf("unknown check: %q", id)
+		}
+		delete(want, id)
+	}
+
+	defs := []*CheckDef{}
+	for _, id := range order {
+		if want[id] {
+			defs = append(defs, registry[id])
+		}
+	}
+	return defs, nil
+}
+
+// splitList parses a comma-separated list of check IDs.
+func splitList(s string) []string {
+	ids := []string{}
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func listChecks(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLEVEL\tWEIGHT\tINPUTS\tDEPENDS ON\tDESCRIPTION")
+	for _, id := range order {
+		d := registry[id]
+		deps := append([]string{}, d.Deps...)
+		sort.Strings(deps)
+		desc := d.Description
+		if d.OptIn {
+			desc += " (opt-in)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", d.ID, d.Level, d.Weight, d.Inputs, strings.Join(deps, ","), desc)
+	}
+	tw.Flush()
+}
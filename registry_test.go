@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// withRegistry replaces the registered checks with defs for the rest of the test.
+func withRegistry(t *testing.T, defs ...*CheckDef) {
+	t.Helper()
+	oldRegistry, oldOrder := registry, order
+	t.Cleanup(func() { registry, order = oldRegistry, oldOrder })
+
+	registry = map[string]*CheckDef{}
+	order = []string{}
+	for _, d := range defs {
+		register(d)
+	}
+}
+
+func ids(defs []*CheckDef) []string {
+	ids := []string{}
+	for _, d := range defs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func TestSelectChecks(t *testing.T) {
+	withRegistry(t,
+		&CheckDef{ID: "clone"},
+		&CheckDef{ID: "sbom", Deps: []string{"clone"}},
+		&CheckDef{ID: "quality", Deps: []string{"sbom"}},
+		&CheckDef{ID: "commits"},
+		&CheckDef{ID: "authors", OptIn: true},
+	)
+
+	tests := []struct {
+		name    string
+		enabled []string
+		skipped []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "defaults leave out opt-in checks", want: []string{"clone", "sbom", "quality", "commits"}},
+		{name: "enabled checks bring their dependencies", enabled: []string{"quality"}, want: []string{"clone", "sbom", "quality"}},
+		{name: "opt-in checks can be enabled", enabled: []string{"authors", "commits"}, want: []string{"commits", "authors"}},
+		{name: "skipped checks are removed", skipped: []string{"commits", "sbom"}, want: []string{"clone", "quality"}},
+		{name: "skipping a dependency of an enabled check", enabled: []string{"quality"}, skipped: []string{"clone"}, want: []string{"sbom", "quality"}},
+		{name: "unknown enabled check", enabled: []string{"nope"}, wantErr: true},
+		{name: "unknown skipped check", skipped: []string{"nope"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defs, err := selectChecks(tc.enabled, tc.skipped)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("selectChecks(%v, %v) = %v, want error", tc.enabled, tc.skipped, ids(defs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectChecks(%v, %v): %v", tc.enabled, tc.skipped, err)
+			}
+			if got := ids(defs); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("selectChecks(%v, %v) = %v, want %v", tc.enabled, tc.skipped, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got, want := splitList(" sbom, ,commits,"), []string{"sbom", "commits"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitList() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDeps(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []*CheckDef
+		wantErr bool
+	}{
+		{
+			name: "acyclic",
+			defs: []*CheckDef{{ID: "a"}, {ID: "b", Deps: []string{"a"}}, {ID: "c", Deps: []string{"a", "b"}}},
+		},
+		{
+			name:    "unknown dependency",
+			defs:    []*CheckDef{{ID: "a", Deps: []string{"nope"}}},
+			wantErr: true,
+		},
+		{
+			name:    "self dependency",
+			defs:    []*CheckDef{{ID: "a", Deps: []string{"a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "cycle",
+			defs:    []*CheckDef{{ID: "a", Deps: []string{"c"}}, {ID: "b", Deps: []string{"a"}}, {ID: "c", Deps: []string{"b"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			withRegistry(t, tc.defs...)
+			if err := validateDeps(); (err != nil) != tc.wantErr {
+				t.Errorf("validateDeps() = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
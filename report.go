@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// reportSchemaVersion is bumped whenever the JSON report changes in a way
+// that consumers need to know about.
+const reportSchemaVersion = 2
+
+// Report is the outcome of running all selected checks against a target.
+type Report struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Version       string `json:"version"`
+	Repo          string `json:"repo"`
+	Path          string `json:"path,omitempty"`
+	URL           string `json:"url,omitempty"`
+	Image         string `json:"image,omitempty"`
+	// Ref is the branch, tag or commit that was analyzed.
+	Ref      string        `json:"ref,omitempty"`
+	Checks   []CheckReport `json:"checks"`
+	Score    int           `json:"score"`
+	MaxScore int           `json:"maxScore"`
+	Percent  int           `json:"percent"`
+	// Level is the highest YOLO level observed in a failing result.
+	Level int `json:"level"`
+}
+
+// CheckReport is the outcome of a single check.
+type CheckReport struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Weight  int      `json:"weight"`
+	Results []Result `json:"results"`
+	Error   string   `json:"error,omitempty"`
+	// RateLimitedUntil is set if the check failed because a rate limit was exhausted.
+	RateLimitedUntil *time.Time `json:"rateLimitedUntil,omitempty"`
+	Skipped          string     `json:"skipped,omitempty"`
+}
+
+func newReport(cf *Config, runs []*checkRun) *Report {
+	rep := &Report{
+		SchemaVersion: reportSchemaVersion,
+		Version:       yolocVersion(),
+		Repo:          cf.Github,
+		Path:          cf.Path,
+		Image:         cf.Image,
+		Ref:           cf.Ref,
+		Checks:        []CheckReport{},
+	}
+	if cf.Path == "" {
+		rep.URL = cf.Forge.RepoURL(cf.Owner, cf.Name)
+	}
+
+	for _, run := range runs {
+		cr := CheckReport{ID: run.Def.ID, Title: run.Def.Title, Weight: run.Def.Weight, Results: []Result{}}
+		if run.Err != nil {
+			cr.Error = run.Err.Error()
+			var rle *RateLimitError
+			if errors.As(run.Err, &rle) {
+				cr.RateLimitedUntil = &rle.Reset
+			}
+			rep.Checks = append(rep.Checks, cr)
+			continue
+		}
+		if run.Skipped != "" {
+			cr.Skipped = run.Skipped
+			rep.Checks = append(rep.Checks, cr)
+			continue
+		}
+
+		for _, r := range run.Outcome.Results {
+			cr.Results = append(cr.Results, r)
+			if r.Max == 0 {
+				continue
+			}
+			rep.Score += r.Score * run.Def.Weight
+			rep.MaxScore += r.Max * run.Def.Weight
+			// For fun, we assign your level to be the highest observed
+			if r.Score > 0 && r.Level > rep.Level {
+				rep.Level = r.Level
+			}
+		}
+		rep.Checks = append(rep.Checks, cr)
+	}
+
+	if rep.Score > 0 {
+		rep.Percent = int((float64(rep.Score) / float64(rep.MaxScore)) * 100)
+	}
+	return rep
+}
+
+func writeJSON(w io.Writer, rep *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	reset := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	runs := []*checkRun{
+		{
+			Def: &CheckDef{ID: "commits", Title: "Commit hygiene", Weight: 2},
+			Outcome: &Outcome{Results: []Result{
+				{Msg: "unsigned", Score: 3, Max: 10, Level: 1},
+				{Msg: "informational"},
+			}},
+		},
+		{
+			Def:     &CheckDef{ID: "private-keys", Title: "Private keys", Weight: 1},
+			Outcome: &Outcome{Results: []Result{{Msg: "keys", Score: 10, Max: 10, Level: 2}}},
+		},
+		{
+			Def: &CheckDef{ID: "releaser", Title: "Automated releases", Weight: 1},
+			Err: fmt.Errorf("query: %w", &RateLimitError{Resource: "graphql", Reset: reset}),
+		},
+		{
+			Def:     &CheckDef{ID: "signed-image", Title: "Signed images", Weight: 1},
+			Skipped: "needs network access",
+		},
+	}
+	cf := &Config{Github: "owner/repo", Ref: "main", Forge: &GitHub{base: defaultGitHubURL}, Owner: "owner", Name: "repo"}
+
+	rep := newReport(cf, runs)
+	if rep.Score != 16 || rep.MaxScore != 30 || rep.Percent != 53 || rep.Level != 2 {
+		t.Errorf("newReport() score = %d/%d (%d%%) level %d, want 16/30 (53%%) level 2", rep.Score, rep.MaxScore, rep.Percent, rep.Level)
+	}
+	if rep.URL != "https://github.com/owner/repo" {
+		t.Errorf("newReport() URL = %q, want https://github.com/owner/repo", rep.URL)
+	}
+	if len(rep.Checks) != len(runs) {
+		t.Fatalf("newReport() has %d checks, want %d", len(rep.Checks), len(runs))
+	}
+	if c := rep.Checks[2]; c.Error == "" || c.RateLimitedUntil == nil || !c.RateLimitedUntil.Equal(reset) {
+		t.Errorf("rate limited check = %+v, want an error and rateLimitedUntil %s", c, reset)
+	}
+	if c := rep.Checks[3]; c.Skipped == "" || len(c.Results) != 0 {
+		t.Errorf("skipped check = %+v, want it skipped without results", c)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, rep); err != nil {
+		t.Fatalf("writeJSON: %v", err)
+	}
+	got := struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Checks        []struct {
+			ID      string          `json:"id"`
+			Results json.RawMessage `json:"results"`
+		} `json:"checks"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, buf.String())
+	}
+	if got.SchemaVersion != reportSchemaVersion {
+		t.Errorf("schemaVersion = %d, want %d", got.SchemaVersion, reportSchemaVersion)
+	}
+	// Consumers can rely on results being an array, even when a check failed.
+	for _, c := range got.Checks {
+		if !bytes.HasPrefix(c.Results, []byte("[")) {
+			t.Errorf("%s results = %s, want an array", c.ID, c.Results)
+		}
+	}
+}
+
+func TestNewReportError(t *testing.T) {
+	rep := newReport(&Config{Path: "."}, []*checkRun{{Def: &CheckDef{ID: "sbom", Weight: 1}, Err: errors.New("boom")}})
+	if c := rep.Checks[0]; c.Error != "boom" || c.RateLimitedUntil != nil {
+		t.Errorf("failed check = %+v, want error boom without a rate limit", c)
+	}
+	if rep.URL != "" || rep.Path != "." {
+		t.Errorf("local report URL, path = %q, %q, want none, .", rep.URL, rep.Path)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Types for the subset of SARIF 2.1.0 that yoloc emits. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string             `json:"level"`
+	Message sarifMessage       `json:"message"`
+	Rule    sarifDescriptorRef `json:"associatedRule"`
+}
+
+type sarifDescriptorRef struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a YOLO level onto a SARIF severity.
+func sarifLevel(level int) string {
+	switch {
+	case level >= 2:
+		return "error"
+	case level == 1:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// newSARIF converts a report into a SARIF log. Each check becomes a rule,
+// and each result that scored YOLO points becomes a SARIF result.
+func newSARIF(rep *Report) *sarifLog {
+	driver := sarifDriver{
+		Name:           "yoloc",
+		Version:        rep.Version,
+		InformationURI: "https://github.com/chainguard-dev/yoloc",
+		Rules:          []sarifRule{},
+	}
+	inv := sarifInvocation{ExecutionSuccessful: true}
+	results := []sarifResult{}
+
+	for i, c := range rep.Checks {
+		desc := c.Title
+		level := 0
+		if d, ok := registry[c.ID]; ok {
+			desc = d.Description
+			level = d.Level
+		}
+
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   c.ID,
+			Name:                 c.Title,
+			ShortDescription:     sarifMessage{Text: c.Title},
+			FullDescription:      sarifMessage{Text: desc},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(level)},
+		})
+
+		if c.Error != "" {
+			inv.ExecutionSuccessful = false
+			inv.ToolExecutionNotifications = append(inv.ToolExecutionNotifications, sarifNotification{
+				Level:   "error",
+				Message: sarifMessage{Text: c.Error},
+				Rule:    sarifDescriptorRef{ID: c.ID},
+			})
+			continue
+		}
+
+		for _, r := range c.Results {
+			if r.Max == 0 || r.Score == 0 {
+				continue
+			}
+
+			sr := sarifResult{
+				RuleID:    c.ID,
+				RuleIndex: i,
+				Level:     sarifLevel(r.Level),
+				Message:   sarifMessage{Text: r.Msg},
+				Locations: []sarifLocation{},
+			}
+
+			for _, l := range r.Locations {
+				sl := sarifLocation{}
+				switch {
+				case l.Path != "":
+					sl.PhysicalLocation.ArtifactLocation.URI = l.Path
+				case l.URL != "":
+					sl.PhysicalLocation.ArtifactLocation.URI = l.URL
+				default:
+					continue
+				}
+				if l.Line > 0 {
+					sl.PhysicalLocation.Region = &sarifRegion{StartLine: l.Line}
+				}
+				sr.Locations = append(sr.Locations, sl)
+			}
+
+			// Repository-level results point at the repository itself.
+			if len(sr.Locations) == 0 && rep.URL != "" {
+				sl := sarifLocation{}
+				sl.PhysicalLocation.ArtifactLocation.URI = rep.URL
+				sr.Locations = append(sr.Locations, sl)
+			}
+
+			results = append(results, sr)
+		}
+	}
+
+	return &sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{
+			{
+				Tool:        sarifTool{Driver: driver},
+				Invocations: []sarifInvocation{inv},
+				Results:     results,
+			},
+		},
+	}
+}
+
+func writeSARIF(w io.Writer, rep *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newSARIF(rep))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSARIF(t *testing.T) {
+	rep := &Report{
+		Version: "v1",
+		URL:     "https://github.com/owner/repo",
+		Checks: []CheckReport{
+			{ID: "releaser", Title: "Automated releases", Results: []Result{{Msg: "cut by humans", Score: 4, Max: 10}}},
+			{ID: "commits", Title: "Commit hygiene", Error: "query: boom"},
+			{ID: "private-keys", Title: "Private keys", Results: []Result{
+				{Msg: "none here", Score: 0, Max: 10, Level: 2},
+				{Msg: "informational", Score: 1},
+				{Msg: "keys", Score: 10, Max: 10, Level: 2, Locations: []Location{
+					{Path: "certs/key.pem"},
+					{Path: ".github/workflows/ci.yml", Line: 12},
+					{URL: "https://github.com/owner/repo/releases/download/v1/key"},
+					{},
+				}},
+			}},
+		},
+	}
+
+	log := newSARIF(rep)
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("newSARIF() = version %s with %d runs, want %s with 1", log.Version, len(log.Runs), sarifVersion)
+	}
+	run := log.Runs[0]
+
+	rules := []string{}
+	for _, r := range run.Tool.Driver.Rules {
+		rules = append(rules, r.ID)
+	}
+	if want := []string{"releaser", "commits", "private-keys"}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %v, want %v", rules, want)
+	}
+	if got := run.Tool.Driver.Rules[2].DefaultConfiguration.Level; got != "error" {
+		t.Errorf("private-keys rule level = %q, want error", got)
+	}
+
+	inv := run.Invocations[0]
+	wantNote := sarifNotification{Level: "error", Message: sarifMessage{Text: "query: boom"}, Rule: sarifDescriptorRef{ID: "commits"}}
+	if inv.ExecutionSuccessful || len(inv.ToolExecutionNotifications) != 1 || inv.ToolExecutionNotifications[0] != wantNote {
+		t.Errorf("invocation = %+v, want unsuccessful with notification %+v", inv, wantNote)
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(run.Results), run.Results)
+	}
+
+	repoLevel := run.Results[0]
+	if repoLevel.RuleID != "releaser" || repoLevel.RuleIndex != 0 || repoLevel.Level != "note" {
+		t.Errorf("first result = %s #%d %s, want releaser #0 note", repoLevel.RuleID, repoLevel.RuleIndex, repoLevel.Level)
+	}
+	if len(repoLevel.Locations) != 1 || repoLevel.Locations[0].PhysicalLocation.ArtifactLocation.URI != rep.URL {
+		t.Errorf("repository-level result locations = %+v, want the repository URL", repoLevel.Locations)
+	}
+
+	keys := run.Results[1]
+	if keys.RuleID != "private-keys" || keys.RuleIndex != 2 || keys.Level != "error" {
+		t.Errorf("second result = %s #%d %s, want private-keys #2 error", keys.RuleID, keys.RuleIndex, keys.Level)
+	}
+	want := []sarifLocation{
+		{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "certs/key.pem"}}},
+		{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: ".github/workflows/ci.yml"}, Region: &sarifRegion{StartLine: 12}}},
+		{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "https://github.com/owner/repo/releases/download/v1/key"}}},
+	}
+	if !reflect.DeepEqual(keys.Locations, want) {
+		t.Errorf("locations = %+v, want %+v", keys.Locations, want)
+	}
+}
+
+func TestSARIFLevel(t *testing.T) {
+	for level, want := range map[int]string{0: "note", 1: "warning", 2: "error", 3: "error"} {
+		if got := sarifLevel(level); got != want {
+			t.Errorf("sarifLevel(%d) = %q, want %q", level, got, want)
+		}
+	}
+}
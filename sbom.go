@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// SBOM formats that can be detected.
+const (
+	formatSPDXJSON      = "SPDX JSON"
+	formatSPDXTagValue  = "SPDX tag-value"
+	formatCycloneDXJSON = "CycloneDX JSON"
+	formatCycloneDXXML  = "CycloneDX XML"
+)
+
+// sbomReleases is the number of recent releases whose assets are examined for SBOMs.
+const sbomReleases = 3
+
+// maxSBOMSize is the largest file that is considered as a possible SBOM.
+const maxSBOMSize = 32 << 20
+
+// sniffSize is how much of a file is read to determine whether it is an SBOM.
+const sniffSize = 64 << 10
+
+// SBOM is a software bill of materials document that was found for the repository.
+type SBOM struct {
+	// Artifact is what the document was found alongside, such as a release tag or "repository".
+	Artifact string
+	Format   string
+	// Path is relative to the checkout, for documents found in the repository.
+	Path string
+	// URL is where the document can be downloaded, for release assets.
+	URL string
+}
+
+var (
+	// sbomNameRE matches the names of files that may be SBOMs.
+	sbomNameRE = regexp.MustCompile(`(?i)(sbom|spdx|cdx|cyclonedx|(^|[._-])bom[._-])`)
+	cdxJSONRE  = regexp.MustCompile(`"bomFormat"\s*:\s*"CycloneDX"`)
+)
+
+// sbomGenerators match workflow and release configuration lines that generate SBOMs.
+var sbomGenerators = []struct {
+	tool string
+	re   *regexp.Regexp
+}{
+	{"syft", regexp.MustCompile(`anchore/sbom-action|\bsyft\b`)},
+	// ko generates and attaches an SBOM to every image it builds unless told not to.
+	{"ko", regexp.MustCompile(`\bko\s+(build|publish|resolve|apply)\b`)},
+	{"goreleaser", regexp.MustCompile(`^sboms:`)},
+	{"cyclonedx", regexp.MustCompile(`cyclonedx-(gomod|npm|py|bom)|CycloneDX/gh-`)},
+	{"bom", regexp.MustCompile(`\bbom generate\b`)},
+}
+
+// sbomFormatByName guesses the format of an SBOM from conventional file extensions.
+func sbomFormatByName(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(n, ".spdx.json"):
+		return formatSPDXJSON
+	case strings.HasSuffix(n, ".spdx"):
+		return formatSPDXTagValue
+	case strings.HasSuffix(n, ".cdx.json"), strings.HasSuffix(n, ".bom.json"):
+		return formatCycloneDXJSON
+	case strings.HasSuffix(n, ".cdx.xml"), strings.HasSuffix(n, ".bom.xml"):
+		return formatCycloneDXXML
+	}
+	return ""
+}
+
+// sniffSBOM determines the format of an SBOM from the start of its contents.
+func sniffSBOM(bs []byte) string {
+	s := string(bs)
+	switch {
+	case strings.Contains(s, `"spdxVersion"`):
+		return formatSPDXJSON
+	case strings.HasPrefix(strings.TrimSpace(s), "SPDXVersion:"):
+		return formatSPDXTagValue
+	case cdxJSONRE.MatchString(s):
+		return formatCycloneDXJSON
+	case strings.Contains(s, "cyclonedx.org/schema/bom"):
+		return formatCycloneDXXML
+	}
+	return ""
+}
+
+// releaseSBOMs returns the SBOMs attached to recent releases.
+func releaseSBOMs(ctx context.Context, c *Config) ([]SBOM, error) {
+	rs, err := c.Forge.Releases(ctx, c.Owner, c.Name, sbomReleases)
+	if err != nil {
+		return nil, fmt.Errorf("releases: %w", err)
+	}
+
+	found := []SBOM{}
+	for _, r := range rs {
+		for _, a := range r.Assets {
+			if !sbomNameRE.MatchString(a.Name) {
+				continue
+			}
+			format := sbomFormatByName(a.Name)
+			if format == "" {
+				bs, err := getLimitCtx(ctx, assetClient, a.URL, sniffSize)
+				if err != nil {
+					klog.Warningf("skipping release asset %s: %v", a.URL, err)
+					continue
+				}
+				format = sniffSBOM(bs)
+			}
+			if format != "" {
+				found = append(found, SBOM{Artifact: r.Tag, Format: format, URL: a.URL})
+			}
+		}
+	}
+	return found, nil
+}
+
+// skipDirs are directories that do not contain the repository's own SBOMs.
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"testdata":     true,
+	"third_party":  true,
+	"vendor":       true,
+}
+
+// treeSBOMs returns the SBOMs checked into the working tree at dir.
+func treeSBOMs(dir string) ([]SBOM, error) {
+	found := []SBOM{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !sbomNameRE.MatchString(d.Name()) {
+			return nil
+		}
+		if fi, err := d.Info(); err != nil || fi.Size() > maxSBOMSize {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		bs, err := io.ReadAll(io.LimitReader(f, sniffSize))
+		if err != nil {
+			return err
+		}
+		if format := sniffSBOM(bs); format != "" {
+			rel, _ := filepath.Rel(dir, path)
+			found = append(found, SBOM{Artifact: "repository", Format: format, Path: filepath.ToSlash(rel)})
+		}
+		return nil
+	})
+	return found, err
+}
+
+// sbomConfigs returns the workflow and release configuration files in dir that may generate SBOMs.
+func sbomConfigs(dir string) ([]string, error) {
+	paths := []string{}
+	for _, pattern := range []string{
+		".github/workflows/*.yml",
+		".github/workflows/*.yaml",
+		".goreleaser.yml",
+		".goreleaser.yaml",
+		".gitlab-ci.yml",
+		"Makefile",
+	} {
+		ms, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, ms...)
+	}
+	return paths, nil
+}
+
+// sbomSteps returns the locations of SBOM generation steps, keyed by the tool used.
+func sbomSteps(dir string) (map[string][]Location, error) {
+	paths, err := sbomConfigs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := map[string][]Location{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		s := bufio.NewScanner(f)
+		for n := 1; s.Scan(); n++ {
+			line := s.Text()
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			for _, g := range sbomGenerators {
+				if g.re.MatchString(line) {
+					steps[g.tool] = append(steps[g.tool], Location{Path: filepath.ToSlash(rel), Line: n})
+				}
+			}
+		}
+		f.Close()
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("read %s: %w", rel, err)
+		}
+	}
+	return steps, nil
+}
+
+// CheckSBOM looks for SBOM documents attached to releases and checked into the
+// repository, and for build steps that generate them.
+func CheckSBOM(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	level := 1
+
+	dest, err := checkout(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if dest == "" {
+		return &Outcome{Results: []Result{{Msg: "unknown ref"}}}, nil
+	}
+
+	sboms := []SBOM{}
+	// Release assets are unavailable when scanning a local path.
+	if c.Path == "" {
+		rs, err := releaseSBOMs(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		sboms = append(sboms, rs...)
+	}
+
+	ts, err := treeSBOMs(dest)
+	if err != nil {
+		return nil, fmt.Errorf("walk: %w", err)
+	}
+	sboms = append(sboms, ts...)
+
+	steps, err := sbomSteps(dest)
+	if err != nil {
+		return nil, fmt.Errorf("workflows: %w", err)
+	}
+
+	found := []string{}
+	locs := []Location{}
+	for _, s := range sboms {
+		where := s.Path
+		if s.URL != "" {
+			where = s.URL[strings.LastIndex(s.URL, "/")+1:]
+			locs = append(locs, Location{URL: s.URL})
+		} else {
+			locs = append(locs, Location{Path: s.Path})
+		}
+		found = append(found, fmt.Sprintf("%s: %s (%s)", s.Artifact, where, s.Format))
+	}
+	for _, g := range sbomGenerators {
+		ls := steps[g.tool]
+		if len(ls) == 0 {
+			continue
+		}
+		found = append(found, fmt.Sprintf("generated by %s in %s", g.tool, ls[0].Path))
+		locs = append(locs, ls...)
+	}
+
+	if len(found) == 0 {
+		return &Outcome{Results: []Result{{Msg: "No SBOMs found in releases, the repository or its workflows", Score: 10, Max: 10, Level: level}}}, nil
+	}
+
+	res := Result{Msg: fmt.Sprintf("Found SBOMs: %s", strings.Join(found, "; ")), Score: 0, Max: 10, Level: level, Locations: locs}
+	return &Outcome{Results: []Result{res}, Facts: Facts{SBOMs: sboms}}, nil
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ociremote "github.com/sigstore/cosign/pkg/oci/remote"
+	"k8s.io/klog/v2"
+)
+
+// ntiaElements are the NTIA minimum elements for an SBOM, in display order.
+var ntiaElements = []string{"supplier", "name", "version", "identifiers", "dependencies", "author", "timestamp"}
+
+// sbomDoc is the part of an SBOM that is graded, independent of its format.
+type sbomDoc struct {
+	Authors       []string
+	Timestamp     string
+	Relationships int
+	Components    []sbomComponent
+}
+
+type sbomComponent struct {
+	Name     string
+	Version  string
+	Supplier string
+	// IDs are unique identifiers, such as purls and CPEs.
+	IDs []string
+}
+
+// assigned reports whether an SPDX field has a real value.
+func assigned(s string) bool {
+	return s != "" && s != "NOASSERTION" && s != "NONE"
+}
+
+// grade returns how completely the document covers each NTIA minimum element, from 0 to 1.
+func (d *sbomDoc) grade() map[string]float64 {
+	g := map[string]float64{}
+	for _, c := range d.Components {
+		if assigned(c.Supplier) {
+			g["supplier"]++
+		}
+		if c.Name != "" {
+			g["name"]++
+		}
+		if assigned(c.Version) {
+			g["version"]++
+		}
+		if len(c.IDs) > 0 {
+			g["identifiers"]++
+		}
+	}
+	if n := float64(len(d.Components)); n > 0 {
+		for _, e := range []string{"supplier", "name", "version", "identifiers"} {
+			g[e] /= n
+		}
+	}
+
+	if d.Relationships > 0 {
+		g["dependencies"] = 1
+	}
+	if len(d.Authors) > 0 {
+		g["author"] = 1
+	}
+	if d.Timestamp != "" {
+		g["timestamp"] = 1
+	}
+	return g
+}
+
+type spdxJSON struct {
+	CreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages []struct {
+		Name         string `json:"name"`
+		VersionInfo  string `json:"versionInfo"`
+		Supplier     string `json:"supplier"`
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+	Relationships []struct {
+		RelationshipType string `json:"relationshipType"`
+	} `json:"relationships"`
+}
+
+func parseSPDXJSON(bs []byte) (*sbomDoc, error) {
+	var s spdxJSON
+	if err := json.Unmarshal(bs, &s); err != nil {
+		return nil, err
+	}
+
+	d := &sbomDoc{Authors: s.CreationInfo.Creators, Timestamp: s.CreationInfo.Created}
+	for _, p := range s.Packages {
+		c := sbomComponent{Name: p.Name, Version: p.VersionInfo, Supplier: p.Supplier}
+		for _, r := range p.ExternalRefs {
+			if isUniqueRef(r.ReferenceType) {
+				c.IDs = append(c.IDs, r.ReferenceLocator)
+			}
+		}
+		d.Components = append(d.Components, c)
+	}
+	for _, r := range s.Relationships {
+		if r.RelationshipType != "DESCRIBES" {
+			d.Relationships++
+		}
+	}
+	return d, nil
+}
+
+// isUniqueRef reports whether an SPDX external reference type identifies a component.
+func isUniqueRef(t string) bool {
+	switch t {
+	case "purl", "cpe22Type", "cpe23Type", "swid":
+		return true
+	}
+	return false
+}
+
+func parseSPDXTagValue(bs []byte) (*sbomDoc, error) {
+	d := &sbomDoc{}
+	var c *sbomComponent
+	s := bufio.NewScanner(bytes.NewReader(bs))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+
+		switch k {
+		case "Creator":
+			d.Authors = append(d.Authors, v)
+		case "Created":
+			d.Timestamp = v
+		case "PackageName":
+			d.Components = append(d.Components, sbomComponent{Name: v})
+			c = &d.Components[len(d.Components)-1]
+		case "Relationship":
+			if !strings.Contains(v, " DESCRIBES ") {
+				d.Relationships++
+			}
+		}
+		if c == nil {
+			continue
+		}
+		switch k {
+		case "PackageVersion":
+			c.Version = v
+		case "PackageSupplier":
+			c.Supplier = v
+		case "ExternalRef":
+			// ExternalRef: <category> <type> <locator>
+			if fs := strings.Fields(v); len(fs) == 3 && isUniqueRef(fs[1]) {
+				c.IDs = append(c.IDs, fs[2])
+			}
+		}
+	}
+	return d, s.Err()
+}
+
+type cdxComponent struct {
+	Name     string `json:"name" xml:"name"`
+	Version  string `json:"version" xml:"version"`
+	Purl     string `json:"purl" xml:"purl"`
+	CPE      string `json:"cpe" xml:"cpe"`
+	Supplier struct {
+		Name string `json:"name" xml:"name"`
+	} `json:"supplier" xml:"supplier"`
+	Publisher string `json:"publisher" xml:"publisher"`
+}
+
+func (cc cdxComponent) component() sbomComponent {
+	c := sbomComponent{Name: cc.Name, Version: cc.Version, Supplier: cc.Supplier.Name}
+	if c.Supplier == "" {
+		c.Supplier = cc.Publisher
+	}
+	for _, id := range []string{cc.Purl, cc.CPE} {
+		if id != "" {
+			c.IDs = append(c.IDs, id)
+		}
+	}
+	return c
+}
+
+type cdxJSON struct {
+	Metadata struct {
+		Timestamp string `json:"timestamp"`
+		Authors   []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		// Tools is an array in CycloneDX 1.4 and earlier, and an object afterwards.
+		Tools json.RawMessage `json:"tools"`
+	} `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+	Dependencies []struct {
+		DependsOn []string `json:"dependsOn"`
+	} `json:"dependencies"`
+}
+
+func parseCycloneDXJSON(bs []byte) (*sbomDoc, error) {
+	var x cdxJSON
+	if err := json.Unmarshal(bs, &x); err != nil {
+		return nil, err
+	}
+
+	d := &sbomDoc{Timestamp: x.Metadata.Timestamp}
+	for _, a := range x.Metadata.Authors {
+		d.Authors = append(d.Authors, a.Name)
+	}
+	// The tool that generated the document is an acceptable author.
+	if t := strings.TrimSpace(string(x.Metadata.Tools)); t != "" && t != "null" && t != "[]" && t != "{}" {
+		d.Authors = append(d.Authors, "tool")
+	}
+	for _, c := range x.Components {
+		d.Components = append(d.Components, c.component())
+	}
+	for _, dep := range x.Dependencies {
+		d.Relationships += len(dep.DependsOn)
+	}
+	return d, nil
+}
+
+type cdxXML struct {
+	Metadata struct {
+		Timestamp string `xml:"timestamp"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"authors>author"`
+		Tools []struct {
+			Name string `xml:"name"`
+		} `xml:"tools>tool"`
+	} `xml:"metadata"`
+	Components   []cdxComponent `xml:"components>component"`
+	Dependencies []struct {
+		DependsOn []struct {
+			Ref string `xml:"ref,attr"`
+		} `xml:"dependency"`
+	} `xml:"dependencies>dependency"`
+}
+
+func parseCycloneDXXML(bs []byte) (*sbomDoc, error) {
+	var x cdxXML
+	if err := xml.Unmarshal(bs, &x); err != nil {
+		return nil, err
+	}
+
+	d := &sbomDoc{Timestamp: x.Metadata.Timestamp}
+	for _, a := range x.Metadata.Authors {
+		d.Authors = append(d.Authors, a.Name)
+	}
+	for _, t := range x.Metadata.Tools {
+		d.Authors = append(d.Authors, t.Name)
+	}
+	for _, c := range x.Components {
+		d.Components = append(d.Components, c.component())
+	}
+	for _, dep := range x.Dependencies {
+		d.Relationships += len(dep.DependsOn)
+	}
+	return d, nil
+}
+
+func parseSBOM(format string, bs []byte) (*sbomDoc, error) {
+	switch format {
+	case formatSPDXJSON:
+		return parseSPDXJSON(bs)
+	case formatSPDXTagValue:
+		return parseSPDXTagValue(bs)
+	case formatCycloneDXJSON:
+		return parseCycloneDXJSON(bs)
+	case formatCycloneDXXML:
+		return parseCycloneDXXML(bs)
+	}
+	return nil, fmt.Errorf("unsupported format: %q", format)
+}
+
+// imageSBOM returns the SBOM attached to an image with cosign, if any.
+func imageSBOM(ctx context.Context, image string) (*SBOM, []byte, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse: %w", err)
+	}
+
+	se, err := ociremote.SignedEntity(ref, ociremote.WithRemoteOptions(remote.WithContext(ctx)))
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := se.Attachment("sbom")
+	if err != nil {
+		klog.V(1).Infof("no sbom attached to %s: %v", image, err)
+		return nil, nil, nil
+	}
+	bs, err := f.Payload()
+	if err != nil {
+		return nil, nil, fmt.Errorf("payload: %w", err)
+	}
+
+	format := sniffSBOM(bs)
+	if format == "" {
+		return nil, nil, nil
+	}
+	return &SBOM{Artifact: image, Format: format}, bs, nil
+}
+
+// CheckSBOMQuality grades the SBOMs found by CheckSBOM, as well as any SBOM
+// attached to the image, against the NTIA minimum elements.
+func CheckSBOMQuality(ctx context.Context, c *Config, in Facts) (*Outcome, error) {
+	dest, err := checkout(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	type doc struct {
+		sbom SBOM
+		data []byte
+	}
+	docs := []doc{}
+
+	for _, s := range in.SBOMs {
+		var bs []byte
+		switch {
+		case s.URL != "":
+			bs, err = getLimitCtx(ctx, assetClient, s.URL, maxSBOMSize+1)
+			if err != nil {
+				klog.Warningf("not grading %s: %v", s.URL, err)
+				continue
+			}
+			if len(bs) > maxSBOMSize {
+				klog.Warningf("not grading %s: larger than %d bytes", s.URL, maxSBOMSize)
+				continue
+			}
+		case s.Path != "" && dest != "":
+			bs, err = os.ReadFile(filepath.Join(dest, filepath.FromSlash(s.Path)))
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", s.Artifact, err)
+		}
+		docs = append(docs, doc{sbom: s, data: bs})
+	}
+
+	// Registries are unavailable when scanning a local path.
+	if c.Image != "" && c.Path == "" {
+		s, bs, err := imageSBOM(ctx, c.Image)
+		if err != nil {
+			klog.Errorf("image sbom for %s: %v", c.Image, err)
+		}
+		if s != nil {
+			docs = append(docs, doc{sbom: *s, data: bs})
+		}
+	}
+
+	if len(docs) == 0 {
+		return &Outcome{Results: []Result{{Msg: "No SBOMs to grade"}}}, nil
+	}
+
+	res := []Result{}
+	for _, d := range docs {
+		where := d.sbom.Artifact
+		locs := []Location{}
+		if d.sbom.Path != "" || d.sbom.URL != "" {
+			locs = append(locs, Location{Path: d.sbom.Path, URL: d.sbom.URL})
+		}
+		switch {
+		case d.sbom.Path != "":
+			where = d.sbom.Path
+		case d.sbom.URL != "":
+			where = fmt.Sprintf("%s: %s", d.sbom.Artifact, d.sbom.URL[strings.LastIndex(d.sbom.URL, "/")+1:])
+		}
+
+		sd, err := parseSBOM(d.sbom.Format, d.data)
+		if err != nil {
+			res = append(res, Result{Msg: fmt.Sprintf("%s is not a valid %s document: %v", where, d.sbom.Format, err), Score: 10, Max: 10, Level: 1, Locations: locs})
+			continue
+		}
+
+		g := sd.grade()
+		total := 0.0
+		missing := []string{}
+		for _, e := range ntiaElements {
+			total += g[e]
+			if g[e] < 1 {
+				missing = append(missing, e)
+			}
+		}
+		complete := total / float64(len(ntiaElements))
+
+		msg := fmt.Sprintf("%s (%s, %d components) covers %.1f%% of the NTIA minimum elements", where, d.sbom.Format, len(sd.Components), complete*100)
+		if len(missing) > 0 {
+			msg = fmt.Sprintf("%s; incomplete: %s", msg, strings.Join(missing, ", "))
+		}
+		res = append(res, Result{Msg: msg, Score: 10 - int(math.Floor(10*complete)), Max: 10, Level: 1, Locations: locs})
+	}
+
+	return &Outcome{Results: res}, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+)
+
+// Each document describes the same two components: one with every element,
+// and one with only a name.
+var sbomFixtures = map[string]string{
+	formatSPDXJSON: `{
+  "spdxVersion": "SPDX-2.3",
+  "creationInfo": {"created": "2022-04-01T00:00:00Z", "creators": ["Tool: syft"]},
+  "packages": [
+    {"name": "app", "versionInfo": "1.0.0", "supplier": "Organization: Acme",
+     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:golang/acme/app@1.0.0"}]},
+    {"name": "lib", "versionInfo": "NOASSERTION", "supplier": "NOASSERTION"}
+  ],
+  "relationships": [
+    {"relationshipType": "DESCRIBES"},
+    {"relationshipType": "DEPENDS_ON"}
+  ]
+}`,
+	formatSPDXTagValue: `SPDXVersion: SPDX-2.3
+Creator: Tool: syft
+Created: 2022-04-01T00:00:00Z
+Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-app
+
+PackageName: app
+PackageVersion: 1.0.0
+PackageSupplier: Organization: Acme
+ExternalRef: PACKAGE-MANAGER purl pkg:golang/acme/app@1.0.0
+
+PackageName: lib
+PackageSupplier: NOASSERTION
+Relationship: SPDXRef-app DEPENDS_ON SPDXRef-lib
+`,
+	formatCycloneDXJSON: `{
+  "bomFormat": "CycloneDX",
+  "metadata": {"timestamp": "2022-04-01T00:00:00Z", "tools": [{"name": "syft"}]},
+  "components": [
+    {"name": "app", "version": "1.0.0", "purl": "pkg:golang/acme/app@1.0.0", "supplier": {"name": "Acme"}},
+    {"name": "lib"}
+  ],
+  "dependencies": [{"ref": "app", "dependsOn": ["lib"]}]
+}`,
+	formatCycloneDXXML: `<?xml version="1.0"?>
+<bom xmlns="http://cyclonedx.org/schema/bom/1.4">
+  <metadata>
+    <timestamp>2022-04-01T00:00:00Z</timestamp>
+    <authors><author><name>Acme</name></author></authors>
+  </metadata>
+  <components>
+    <component><name>app</name><version>1.0.0</version><publisher>Acme</publisher><cpe>cpe:2.3:a:acme:app:1.0.0:*:*:*:*:*:*:*</cpe></component>
+    <component><name>lib</name></component>
+  </components>
+  <dependencies>
+    <dependency ref="app"><dependency ref="lib"/></dependency>
+  </dependencies>
+</bom>`,
+}
+
+func TestParseSBOM(t *testing.T) {
+	want := map[string]float64{
+		"supplier":     0.5,
+		"name":         1,
+		"version":      0.5,
+		"identifiers":  0.5,
+		"dependencies": 1,
+		"author":       1,
+		"timestamp":    1,
+	}
+
+	for format, doc := range sbomFixtures {
+		t.Run(format, func(t *testing.T) {
+			if got := sniffSBOM([]byte(doc)); got != format {
+				t.Errorf("sniffSBOM() = %q, want %q", got, format)
+			}
+
+			d, err := parseSBOM(format, []byte(doc))
+			if err != nil {
+				t.Fatalf("parseSBOM: %v", err)
+			}
+			if len(d.Components) != 2 {
+				t.Fatalf("got %d components, want 2", len(d.Components))
+			}
+
+			g := d.grade()
+			for _, e := range ntiaElements {
+				if g[e] != want[e] {
+					t.Errorf("grade()[%q] = %v, want %v", e, g[e], want[e])
+				}
+			}
+		})
+	}
+}
+
+func TestGradeEmpty(t *testing.T) {
+	g := (&sbomDoc{}).grade()
+	for _, e := range ntiaElements {
+		if g[e] != 0 {
+			t.Errorf("grade()[%q] = %v for an empty document, want 0", e, g[e])
+		}
+	}
+}
+
+func TestParseSBOMInvalid(t *testing.T) {
+	for _, format := range []string{formatSPDXJSON, formatCycloneDXJSON, formatCycloneDXXML} {
+		if _, err := parseSBOM(format, []byte("not a document")); err == nil {
+			t.Errorf("parseSBOM(%q) of garbage succeeded, want error", format)
+		}
+	}
+	if _, err := parseSBOM("SWID", nil); err == nil {
+		t.Error("parseSBOM of an unsupported format succeeded, want error")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Facts are discovered by one check and handed to the checks that depend on it.
+type Facts struct {
+	// Images are container images that appear to be published by the repository.
+	Images []string
+	// SBOMs are the SBOM documents found for the repository.
+	SBOMs []SBOM
+	// Workflows are the GitHub Actions workflows parsed from a checkout.
+	Workflows []*Workflow
+	// WorkflowsLoaded is set once the workflows have been parsed, even if
+	// there are none.
+	WorkflowsLoaded bool
+}
+
+func (f *Facts) merge(o Facts) {
+	f.Images = append(f.Images, o.Images...)
+	f.SBOMs = append(f.SBOMs, o.SBOMs...)
+	f.Workflows = append(f.Workflows, o.Workflows...)
+	f.WorkflowsLoaded = f.WorkflowsLoaded || o.WorkflowsLoaded
+}
+
+// Outcome is what a Checker produces.
+type Outcome struct {
+	Results []Result
+	Facts   Facts
+}
+
+// checkRun records the outcome of running a single check.
+type checkRun struct {
+	Def     *CheckDef
+	Outcome *Outcome
+	Err     error
+	// Skipped explains why the check was not run.
+	Skipped string
+}
+
+// schedule runs defs, starting each check as soon as the checks it depends on
+// have finished. Checks that do not depend on each other run concurrently.
+// Dependencies that are not part of defs are ignored. The returned runs are
+// in the same order as defs.
+func schedule(ctx context.Context, cf *Config, defs []*CheckDef) []*checkRun {
+	runs := make([]*checkRun, len(defs))
+	index := map[string]int{}
+	done := map[string]chan struct{}{}
+	for i, d := range defs {
+		index[d.ID] = i
+		done[d.ID] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for i, d := range defs {
+		wg.Add(1)
+		go func(i int, d *CheckDef) {
+			defer wg.Done()
+			defer close(done[d.ID])
+
+			in := Facts{}
+			for _, dep := range d.Deps {
+				ch, ok := done[dep]
+				if !ok {
+					continue
+				}
+				<-ch
+				if r := runs[index[dep]]; r.Outcome != nil {
+					in.merge(r.Outcome.Facts)
+				}
+			}
+
+			if cf.Path != "" && d.Inputs.networked() {
+				runs[i] = &checkRun{Def: d, Skipped: "needs network access, which is unavailable when scanning a local path"}
+				return
+			}
+
+			o, err := runCheck(ctx, cf, d, in)
+			runs[i] = &checkRun{Def: d, Outcome: o, Err: err}
+		}(i, d)
+	}
+
+	wg.Wait()
+	return runs
+}
+
+// runCheck runs a single check, consulting the persistence layer first.
+func runCheck(ctx context.Context, cf *Config, d *CheckDef, in Facts) (*Outcome, error) {
+	// A local checkout may change at any time, so never persist its results.
+	if cf.Path != "" {
+		return d.Run(ctx, cf, in)
+	}
+
+	// Keys for github.com repositories predate support for other forges and refs.
+	key := fmt.Sprintf("%s@%s", cf.Github, d.ID)
+	if cf.Ref != cf.DefaultBranch {
+		key = fmt.Sprintf("%s#%s", key, cf.Ref)
+	}
+	if d.History {
+		key = fmt.Sprintf("%s~%s", key, asSha256(cf.History)[:12])
+	}
+	if h := cf.Forge.Host(); h != "github.com" {
+		key = fmt.Sprintf("%s/%s", h, key)
+	}
+
+	o, err := cf.Persist.Get(ctx, key)
+	if err != nil {
+		klog.Errorf("get err: %v", err)
+	}
+	if err == nil && o != nil {
+		return o, nil
+	}
+
+	o, err = d.Run(ctx, cf, in)
+	if err != nil {
+		if _, ok := cf.Persist.(*NullPersister); !ok {
+			klog.Errorf("not caching error: %v", err)
+		}
+		return nil, err
+	}
+
+	if err := cf.Persist.Set(ctx, key, o); err != nil {
+		klog.Errorf("set err: %v", err)
+	}
+	return o, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	var mu sync.Mutex
+	ran := []string{}
+	// record returns a check that notes it ran, along with the facts it was given.
+	record := func(id string, out Facts) Checker {
+		return func(_ context.Context, _ *Config, in Facts) (*Outcome, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, id)
+			return &Outcome{Results: []Result{{Msg: id}}, Facts: mergeFacts(in, out)}, nil
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		defs []*CheckDef
+		// wantFacts are the images each check ran with, including its own.
+		wantFacts   map[string][]string
+		wantSkipped []string
+		wantOrder   [][2]string
+	}{
+		{
+			name: "dependencies run first and hand over their facts",
+			defs: []*CheckDef{
+				{ID: "quality", Deps: []string{"sbom", "images"}, Run: record("quality", Facts{})},
+				{ID: "sbom", Deps: []string{"images"}, Run: record("sbom", Facts{Images: []string{"sbom"}})},
+				{ID: "images", Run: record("images", Facts{Images: []string{"images"}})},
+			},
+			wantFacts: map[string][]string{
+				"images":  {"images"},
+				"sbom":    {"images", "sbom"},
+				"quality": {"images", "sbom", "images"},
+			},
+			wantOrder: [][2]string{{"images", "sbom"}, {"sbom", "quality"}},
+		},
+		{
+			name: "dependencies that were not selected are ignored",
+			defs: []*CheckDef{
+				{ID: "quality", Deps: []string{"sbom"}, Run: record("quality", Facts{Images: []string{"quality"}})},
+			},
+			wantFacts: map[string][]string{"quality": {"quality"}},
+		},
+		{
+			name: "networked checks are skipped for a local path, and their dependents run without their facts",
+			path: "/src",
+			defs: []*CheckDef{
+				{ID: "releases", Inputs: NeedsRepo, Run: record("releases", Facts{Images: []string{"releases"}})},
+				{ID: "tree", Inputs: NeedsClone, Deps: []string{"releases"}, Run: record("tree", Facts{Images: []string{"tree"}})},
+			},
+			wantFacts:   map[string][]string{"tree": {"tree"}},
+			wantSkipped: []string{"releases"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ran = []string{}
+			withRegistry(t, tc.defs...)
+			cf := &Config{Path: tc.path, Forge: &GitHub{base: defaultGitHubURL}, Persist: &NullPersister{}}
+
+			runs := schedule(context.Background(), cf, tc.defs)
+
+			var skipped []string
+			for i, r := range runs {
+				if r.Def != tc.defs[i] {
+					t.Errorf("run %d is for %s, want %s", i, r.Def.ID, tc.defs[i].ID)
+				}
+				if r.Skipped != "" {
+					skipped = append(skipped, r.Def.ID)
+					continue
+				}
+				if r.Err != nil {
+					t.Errorf("%s: %v", r.Def.ID, r.Err)
+					continue
+				}
+				if got := r.Outcome.Facts.Images; !reflect.DeepEqual(got, tc.wantFacts[r.Def.ID]) {
+					t.Errorf("%s facts = %v, want %v", r.Def.ID, got, tc.wantFacts[r.Def.ID])
+				}
+			}
+			if !reflect.DeepEqual(skipped, tc.wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, tc.wantSkipped)
+			}
+
+			pos := map[string]int{}
+			for i, id := range ran {
+				pos[id] = i
+			}
+			for _, o := range tc.wantOrder {
+				if pos[o[0]] > pos[o[1]] {
+					t.Errorf("%s ran after %s: %v", o[0], o[1], ran)
+				}
+			}
+		})
+	}
+}
+
+// mergeFacts returns the facts of a followed by those of b.
+func mergeFacts(a, b Facts) Facts {
+	f := Facts{}
+	f.merge(a)
+	f.merge(b)
+	return f
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/json"
 	"html/template"
 	"net/http"
 	"runtime"
@@ -12,7 +13,6 @@ import (
 
 	"github.com/buildkite/terminal-to-html/v3"
 	lru "github.com/hnlq715/golang-lru"
-	"github.com/shurcooL/githubv4"
 	"k8s.io/klog/v2"
 )
 
@@ -20,25 +20,28 @@ import (
 var yoloTmpl string
 
 type ServerConfig struct {
-	Addr     string
-	V4Client *githubv4.Client
-	Cache    *lru.ARCCache
-	Persist  Persister
+	Addr    string
+	Forges  *Forges
+	Cache   *lru.ARCCache
+	Persist Persister
+	History HistoryOptions
 }
 
 func serve(_ context.Context, sc *ServerConfig) {
-	s := &Server{V4Client: sc.V4Client, Cache: sc.Cache, Persist: sc.Persist}
+	s := &Server{Forges: sc.Forges, Cache: sc.Cache, Persist: sc.Persist, History: sc.History}
 	http.HandleFunc("/", s.Root())
 	http.HandleFunc("/healthz", s.Healthz())
 	http.HandleFunc("/threadz", s.Threadz())
+	http.HandleFunc("/ratez", s.Ratez())
 	klog.Infof("Listening on %s ...", sc.Addr)
 	http.ListenAndServe(sc.Addr, nil)
 }
 
 type Server struct {
-	V4Client *githubv4.Client
-	Cache    *lru.ARCCache
-	Persist  Persister
+	Forges  *Forges
+	Cache   *lru.ARCCache
+	Persist Persister
+	History HistoryOptions
 }
 
 func (s *Server) Root() http.HandlerFunc {
@@ -65,6 +68,20 @@ func (s *Server) Root() http.HandlerFunc {
 			work = true
 		}
 
+		ref := ""
+		if len(r.URL.Query()["ref"]) > 0 {
+			ref = r.URL.Query()["ref"][0]
+		}
+
+		checks := splitList(*checksFlag)
+		if len(r.URL.Query()["checks"]) > 0 {
+			checks = splitList(r.URL.Query()["checks"][0])
+		}
+		skip := splitList(*skipFlag)
+		if len(r.URL.Query()["skip-checks"]) > 0 {
+			skip = splitList(r.URL.Query()["skip-checks"][0])
+		}
+
 		if !strings.Contains(repo, "/") {
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -77,13 +94,22 @@ func (s *Server) Root() http.HandlerFunc {
 
 		if work {
 			klog.Infof("Running checks for %s / %s", repo, image)
-			runChecks(r.Context(), bw, &Config{
-				Github:   repo,
-				Image:    image,
-				V4Client: s.V4Client,
-				Cache:    s.Cache,
-				Persist:  s.Persist,
+			rep, err := runChecks(r.Context(), &Config{
+				Github:     repo,
+				Image:      image,
+				Ref:        ref,
+				History:    s.History,
+				Forges:     s.Forges,
+				Cache:      s.Cache,
+				Persist:    s.Persist,
+				Checks:     checks,
+				SkipChecks: skip,
 			})
+			if err != nil {
+				bw.Write([]byte(err.Error() + "\n"))
+			} else {
+				printReport(bw, rep)
+			}
 		} else {
 			bw.Write([]byte("Patiently waiting for you to click that YOLO! button ...\n"))
 		}
@@ -133,6 +159,17 @@ func (s *Server) Threadz() http.HandlerFunc {
 	}
 }
 
+// Ratez reports the remaining GitHub API budget, so that operators can tell when it is running low.
+func (s *Server) Ratez() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(s.Forges.Budgets()); err != nil {
+			klog.Errorf("writing ratez response: %v", err)
+		}
+	}
+}
+
 func stack() []byte {
 	buf := make([]byte, 1024)
 	for {
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a GitHub Actions workflow parsed from a checkout. Nodes are
+// kept, rather than decoded values, so that findings can point at a line.
+type Workflow struct {
+	// Path is relative to the checkout.
+	Path string
+	// On is the trigger definition: a string, list or mapping.
+	On *yaml.Node
+	// Permissions is the top-level permissions block, if any.
+	Permissions *yaml.Node
+	Env         *yaml.Node
+	Jobs        []*Job
+
+	// src is the workflow as read, which is what is persisted.
+	src []byte
+}
+
+// Job is a single job within a workflow.
+type Job struct {
+	ID          string
+	Line        int
+	Permissions *yaml.Node
+	Env         *yaml.Node
+	// Uses is set for jobs that call a reusable workflow.
+	Uses  *yaml.Node
+	Steps []*Step
+}
+
+// Step is a single step within a job.
+type Step struct {
+	// Index is the 1-based position of the step within its job.
+	Index int
+	Name  string
+	Line  int
+	Uses  *yaml.Node
+	Run   *yaml.Node
+	With  *yaml.Node
+	Env   *yaml.Node
+}
+
+// Label describes the step for use in messages.
+func (s *Step) Label() string {
+	switch {
+	case s.Name != "":
+		return fmt.Sprintf("%q", s.Name)
+	case s.Uses != nil:
+		return s.Uses.Value
+	}
+	return fmt.Sprintf("#%d", s.Index)
+}
+
+// where describes a location within a workflow for use in messages.
+func (w *Workflow) where(j *Job, s *Step) string {
+	switch {
+	case s != nil:
+		return fmt.Sprintf("%s job %s step %s", w.Path, j.ID, s.Label())
+	case j != nil:
+		return fmt.Sprintf("%s job %s", w.Path, j.ID)
+	}
+	return w.Path
+}
+
+// persistedWorkflow is the gob encoding of a Workflow. Only the source is
+// kept, as parsed nodes are many times larger.
+type persistedWorkflow struct {
+	Path   string
+	Source []byte
+}
+
+func (w *Workflow) GobEncode() ([]byte, error) {
+	var bs bytes.Buffer
+	if err := gob.NewEncoder(&bs).Encode(persistedWorkflow{Path: w.Path, Source: w.src}); err != nil {
+		return nil, err
+	}
+	return bs.Bytes(), nil
+}
+
+func (w *Workflow) GobDecode(bs []byte) error {
+	pw := persistedWorkflow{}
+	if err := gob.NewDecoder(bytes.NewReader(bs)).Decode(&pw); err != nil {
+		return err
+	}
+	parsed, err := parseWorkflow(pw.Path, pw.Source)
+	if err != nil {
+		return err
+	}
+	*w = *parsed
+	return nil
+}
+
+// Triggers returns the names of the events that run the workflow.
+func (w *Workflow) Triggers() map[string]bool {
+	ts := map[string]bool{}
+	if w.On == nil {
+		return ts
+	}
+	switch w.On.Kind {
+	case yaml.ScalarNode:
+		ts[w.On.Value] = true
+	case yaml.SequenceNode:
+		for _, n := range w.On.Content {
+			ts[n.Value] = true
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(w.On.Content); i += 2 {
+			ts[w.On.Content[i].Value] = true
+		}
+	}
+	return ts
+}
+
+// mapValue returns the value for key in a mapping node, or nil.
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// pairs returns the entries of a mapping node as "key: value" lines.
+func pairs(n *yaml.Node) []string {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	ps := []string{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		ps = append(ps, fmt.Sprintf("%s: %s", n.Content[i].Value, n.Content[i+1].Value))
+	}
+	return ps
+}
+
+// Text returns what a step uses and runs, along with its inputs and
+// environment, for pattern matching.
+func (s *Step) Text() string {
+	parts := []string{}
+	if s.Uses != nil {
+		parts = append(parts, s.Uses.Value)
+	}
+	parts = append(parts, pairs(s.With)...)
+	parts = append(parts, pairs(s.Env)...)
+	if s.Run != nil {
+		parts = append(parts, s.Run.Value)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// parseWorkflow parses the workflow in bs. path is only used for display.
+func parseWorkflow(path string, bs []byte) (*Workflow, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		return nil, err
+	}
+	w := &Workflow{Path: path, src: bs}
+	if len(doc.Content) == 0 {
+		return w, nil
+	}
+
+	root := doc.Content[0]
+	w.On = mapValue(root, "on")
+	w.Permissions = mapValue(root, "permissions")
+	w.Env = mapValue(root, "env")
+
+	jobs := mapValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return w, nil
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		jn := jobs.Content[i+1]
+		j := &Job{
+			ID:          jobs.Content[i].Value,
+			Line:        jobs.Content[i].Line,
+			Permissions: mapValue(jn, "permissions"),
+			Env:         mapValue(jn, "env"),
+			Uses:        mapValue(jn, "uses"),
+		}
+
+		if steps := mapValue(jn, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+			for k, sn := range steps.Content {
+				s := &Step{
+					Index: k + 1,
+					Line:  sn.Line,
+					Uses:  mapValue(sn, "uses"),
+					Run:   mapValue(sn, "run"),
+					With:  mapValue(sn, "with"),
+					Env:   mapValue(sn, "env"),
+				}
+				if name := mapValue(sn, "name"); name != nil {
+					s.Name = name.Value
+				}
+				j.Steps = append(j.Steps, s)
+			}
+		}
+		w.Jobs = append(w.Jobs, j)
+	}
+	return w, nil
+}
+
+// loadWorkflows parses the GitHub Actions workflows in the checkout at dir.
+// Files that are not valid YAML are skipped, as GitHub would refuse to run them.
+func loadWorkflows(dir string) ([]*Workflow, error) {
+	paths := []string{}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		ms, err := filepath.Glob(filepath.Join(dir, ".github", "workflows", pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, ms...)
+	}
+	sort.Strings(paths)
+
+	ws := []*Workflow{}
+	for _, p := range paths {
+		bs, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		w, err := parseWorkflow(filepath.ToSlash(rel), bs)
+		if err != nil {
+			continue
+		}
+		ws = append(ws, w)
+	}
+	return ws, nil
+}
+
+// CheckWorkflows parses the GitHub Actions workflows in a checkout of the
+// repository, for the checks that examine them.
+func CheckWorkflows(ctx context.Context, c *Config, _ Facts) (*Outcome, error) {
+	dest, err := checkout(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if dest == "" {
+		return &Outcome{Results: []Result{{Msg: "unknown ref"}}}, nil
+	}
+
+	ws, err := loadWorkflows(dest)
+	if err != nil {
+		return nil, fmt.Errorf("workflows: %w", err)
+	}
+	if len(ws) == 0 {
+		return &Outcome{
+			Results: []Result{{Msg: "No GitHub Actions workflows found"}},
+			Facts:   Facts{WorkflowsLoaded: true},
+		}, nil
+	}
+
+	paths := []string{}
+	for _, w := range ws {
+		paths = append(paths, w.Path)
+	}
+	return &Outcome{
+		Results: []Result{{Msg: fmt.Sprintf("Found %d GitHub Actions workflows: %s", len(ws), listed(paths))}},
+		Facts:   Facts{Workflows: ws, WorkflowsLoaded: true},
+	}, nil
+}
+
+// workflowsFrom returns the workflows handed to a check that depends on the
+// workflows check. If there are none, it returns the empty outcome for the
+// check to report, as the workflows check already says so. If they were never
+// loaded, it returns an error, so that the check cannot pass without looking.
+func workflowsFrom(in Facts) ([]*Workflow, *Outcome, error) {
+	if !in.WorkflowsLoaded {
+		return nil, nil, errors.New("workflows were not loaded: the workflows check was skipped or failed")
+	}
+	if len(in.Workflows) == 0 {
+		return nil, &Outcome{}, nil
+	}
+	return in.Workflows, nil, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+const testWorkflow = `name: ci
+on:
+  push:
+  pull_request_target:
+permissions:
+  contents: read
+jobs:
+  build:
+    steps:
+      - uses: actions/checkout@v3
+      - name: Test
+        run: make test
+        env:
+          CGO_ENABLED: "0"
+  release:
+    uses: acme/workflows/.github/workflows/release.yml@main
+`
+
+func TestParseWorkflow(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/ci.yml", []byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := w.Triggers(), map[string]bool{"push": true, "pull_request_target": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Triggers() = %v, want %v", got, want)
+	}
+	if len(w.Jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(w.Jobs))
+	}
+
+	build := w.Jobs[0]
+	if build.ID != "build" || build.Line != 8 || len(build.Steps) != 2 {
+		t.Fatalf("build job = %s at line %d with %d steps, want build at line 8 with 2 steps", build.ID, build.Line, len(build.Steps))
+	}
+	if got := build.Steps[0].Label(); got != "actions/checkout@v3" {
+		t.Errorf("first step label = %q, want the action", got)
+	}
+	if got := build.Steps[1].Label(); got != `"Test"` {
+		t.Errorf("second step label = %q, want its name", got)
+	}
+	if got, want := build.Steps[1].Text(), "CGO_ENABLED: 0\nmake test"; got != want {
+		t.Errorf("second step text = %q, want %q", got, want)
+	}
+
+	if release := w.Jobs[1]; release.Uses == nil || release.Uses.Line != 16 {
+		t.Errorf("release job does not call a reusable workflow at line 16: %+v", release.Uses)
+	}
+}
+
+func TestTriggerForms(t *testing.T) {
+	for _, on := range []string{"on: push", "on: [push]", "on:\n  push:\n    branches: [main]"} {
+		w, err := parseWorkflow("wf.yml", []byte(on))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Triggers(); !reflect.DeepEqual(got, map[string]bool{"push": true}) {
+			t.Errorf("Triggers() for %q = %v, want push", on, got)
+		}
+	}
+}
+
+func TestWorkflowFactsPersist(t *testing.T) {
+	w, err := parseWorkflow(".github/workflows/ci.yml", []byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bs bytes.Buffer
+	if err := gob.NewEncoder(&bs).Encode(&Blob{Facts: Facts{Workflows: []*Workflow{w}}}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	bl := &Blob{}
+	if err := gob.NewDecoder(&bs).Decode(bl); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(bl.Facts.Workflows) != 1 {
+		t.Fatalf("got %d workflows, want 1", len(bl.Facts.Workflows))
+	}
+	got := bl.Facts.Workflows[0]
+	if got.Path != w.Path || len(got.Jobs) != 2 || got.Jobs[0].Steps[1].Run.Value != "make test" {
+		t.Errorf("decoded workflow = %+v, want %+v", got, w)
+	}
+}
+
+func TestWorkflowDependents(t *testing.T) {
+	checks := map[string]func(context.Context, *Config, Facts) (*Outcome, error){
+		"action-pinning":    CheckActionPinning,
+		"script-injection":  CheckScriptInjection,
+		"token-permissions": CheckTokenPermissions,
+		"ci-credentials":    CheckCICredentials,
+	}
+	for id, check := range checks {
+		// Without the workflows check, there is nothing to pass on.
+		if o, err := check(context.Background(), &Config{}, Facts{}); err == nil {
+			t.Errorf("%s without loaded workflows = %+v, want error", id, o)
+		}
+
+		o, err := check(context.Background(), &Config{}, Facts{WorkflowsLoaded: true})
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if len(o.Results) != 0 {
+			t.Errorf("%s without workflows = %+v, want no results", id, o.Results)
+		}
+	}
+}